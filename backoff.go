@@ -0,0 +1,64 @@
+package proc
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff computes exponentially growing delays between retries of an
+// operation, such as re-running a command or polling for readiness. The
+// zero value is ready to use with the defaults documented on each field.
+//
+// A Backoff is not safe for concurrent use.
+type Backoff struct {
+	// Base is the delay returned by the first call to Next.
+	// If <= 0, defaults to 100ms.
+	Base time.Duration
+	// Max caps every delay, including jitter.
+	// If <= 0, defaults to 30s.
+	Max time.Duration
+	// Factor multiplies the delay after each call to Next.
+	// If < 1, defaults to 2.
+	Factor float64
+	// Jitter randomizes each delay by up to ±Jitter of its value, so that
+	// retries of many callers spread out. It is clamped to [0, 1];
+	// 0 disables jitter.
+	Jitter float64
+
+	// attempt is the number of delays returned since the last Reset
+	attempt int
+}
+
+// Next returns the delay to wait before the next attempt and advances the
+// backoff.
+func (b *Backoff) Next() time.Duration {
+	base := float64(b.Base)
+	if base <= 0 {
+		base = float64(100 * time.Millisecond)
+	}
+	maxDelay := float64(b.Max)
+	if maxDelay <= 0 {
+		maxDelay = float64(30 * time.Second)
+	}
+	factor := b.Factor
+	if factor < 1 {
+		factor = 2
+	}
+
+	d := math.Min(base*math.Pow(factor, float64(b.attempt)), maxDelay)
+	if d < maxDelay {
+		// stop counting once capped so the exponent cannot overflow
+		b.attempt++
+	}
+
+	if jitter := math.Min(math.Max(b.Jitter, 0), 1); jitter > 0 {
+		d += d * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(math.Min(d, maxDelay))
+}
+
+// Reset restarts the backoff from Base.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
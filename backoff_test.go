@@ -0,0 +1,69 @@
+package proc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_Defaults(t *testing.T) {
+	var b Backoff
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+	for i, w := range want {
+		if got := b.Next(); got != w {
+			t.Fatalf("Next #%d = %v, want %v", i, got, w)
+		}
+	}
+}
+
+func TestBackoff_Cap(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: 5 * time.Second, Factor: 3}
+	want := []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := b.Next(); got != w {
+			t.Fatalf("Next #%d = %v, want %v", i, got, w)
+		}
+	}
+
+	// many more attempts must stay capped without overflowing
+	for range 1000 {
+		if got := b.Next(); got != 5*time.Second {
+			t.Fatalf("Next after cap = %v, want 5s", got)
+		}
+	}
+}
+
+func TestBackoff_Reset(t *testing.T) {
+	b := Backoff{Base: 10 * time.Millisecond}
+	b.Next()
+	b.Next()
+	b.Reset()
+	if got := b.Next(); got != 10*time.Millisecond {
+		t.Fatalf("Next after Reset = %v, want 10ms", got)
+	}
+}
+
+func TestBackoff_JitterBounds(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: time.Minute, Factor: 1, Jitter: 0.25}
+	var varied bool
+	for range 1000 {
+		d := b.Next()
+		if d < 750*time.Millisecond || d > 1250*time.Millisecond {
+			t.Fatalf("jittered delay %v outside [750ms, 1250ms]", d)
+		}
+		if d != time.Second {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Fatal("jitter should vary the delays")
+	}
+}
+
+func TestBackoff_JitterNeverExceedsMax(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: time.Second, Jitter: 1}
+	for range 1000 {
+		if d := b.Next(); d < 0 || d > time.Second {
+			t.Fatalf("jittered delay %v outside [0, 1s]", d)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package proc
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"syscall"
 	"testing"
 )
 
@@ -35,6 +37,22 @@ func BenchmarkDebugf(b *testing.B) {
 	}
 }
 
+// BenchmarkDebugfGuarded measures logging disabled via io.Discard when the
+// call site checks IsDebug first, which must not allocate
+func BenchmarkDebugfGuarded(b *testing.B) {
+	old := Logger
+	Logger = io.Discard
+	defer func() { Logger = old }()
+
+	b.ReportAllocs()
+
+	for i := 0; b.Loop(); i++ {
+		if IsDebug() {
+			debugf("test message %d", i)
+		}
+	}
+}
+
 // BenchmarkDebugfWithBuffer measures logging performance with real buffer
 func BenchmarkDebugfWithBuffer(b *testing.B) {
 	old := Logger
@@ -80,3 +98,24 @@ func BenchmarkContext(b *testing.B) {
 		_ = Context()
 	}
 }
+
+// BenchmarkNotifyConcurrency compares dispatching to many listeners with
+// and without a limit set by SetNotifyConcurrency
+func BenchmarkNotifyConcurrency(b *testing.B) {
+	var ids []uint32
+	for range 200 {
+		ids = append(ids, On(syscall.SIGALRM, func() {}))
+	}
+	defer Cancel(ids...)
+	defer SetNotifyConcurrency(0)
+
+	for _, limit := range []int{0, 4, 16} {
+		b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+			SetNotifyConcurrency(limit)
+			b.ReportAllocs()
+			for b.Loop() {
+				Notify(syscall.SIGALRM)
+			}
+		})
+	}
+}
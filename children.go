@@ -0,0 +1,80 @@
+package proc
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// ChildInfo describes a command started by Exec that is still running.
+type ChildInfo struct {
+	// Pid is the process ID of the command.
+	Pid int
+	// Command and Args are the command and arguments it was started with.
+	Command string
+	Args    []string
+	// StartedAt is when the command was started.
+	StartedAt time.Time
+}
+
+// child is a running command registered by trackChild.
+type child struct {
+	info ChildInfo
+	kill func() error
+}
+
+var (
+	// childrenMu protects children.
+	childrenMu sync.Mutex
+	// children holds the running commands started by Exec, by PID.
+	children = map[int]child{}
+)
+
+// RunningChildren returns the commands started by Exec, Run or Start that
+// are still running, oldest first, e.g. for a supervisor dashboard. A
+// command is listed from right after it started until it has been waited
+// for.
+func RunningChildren() []ChildInfo {
+	childrenMu.Lock()
+	list := make([]ChildInfo, 0, len(children))
+	for _, c := range children {
+		list = append(list, c.info)
+	}
+	childrenMu.Unlock()
+
+	slices.SortFunc(list, func(a, b ChildInfo) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return list
+}
+
+// trackChild registers a started command with RunningChildren, with kill
+// terminating it at once. The returned function removes it once the
+// command has been waited for.
+func trackChild(info ChildInfo, kill func() error) (untrack func()) {
+	info.Args = slices.Clone(info.Args)
+	childrenMu.Lock()
+	children[info.Pid] = child{info: info, kill: kill}
+	childrenMu.Unlock()
+	return func() {
+		childrenMu.Lock()
+		delete(children, info.Pid)
+		childrenMu.Unlock()
+	}
+}
+
+// killChildren terminates the running commands started by Exec, and
+// reports whether there were any.
+func killChildren() bool {
+	childrenMu.Lock()
+	kills := make([]func() error, 0, len(children))
+	for _, c := range children {
+		kills = append(kills, c.kill)
+	}
+	childrenMu.Unlock()
+
+	for _, kill := range kills {
+		_ = kill()
+	}
+	return len(kills) > 0
+}
@@ -0,0 +1,5 @@
+package proc
+
+// daemonEnv is the environment variable that tells a re-executed copy of
+// the program which stage of Daemonize it is running in.
+const daemonEnv = "PROC_DAEMON"
@@ -0,0 +1,69 @@
+//go:build !windows
+// +build !windows
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Daemonize detaches the program from its controlling terminal and keeps it
+// running in the background. Since Go cannot safely fork, the traditional
+// double fork is emulated by re-executing the binary twice:
+//
+//  1. The original process starts a copy of itself in a new session
+//     (setsid) and exits.
+//  2. That session leader starts the final copy, which is not a session
+//     leader and therefore can never reacquire a controlling terminal, and
+//     exits too.
+//  3. The final copy clears its umask and returns nil: it is the daemon.
+//
+// Every copy gets the same arguments, "/" as its working directory and
+// stdio redirected to /dev/null. The PROC_DAEMON environment variable tells
+// a copy which stage it is in; it is removed again in the daemon so that
+// commands started from it are unaffected. Everything before the call runs
+// once per stage, so Daemonize should be called early in main.
+func Daemonize() error {
+	switch os.Getenv(daemonEnv) {
+	case "":
+		return respawn("1", true)
+	case "1":
+		return respawn("2", false)
+	default:
+		syscall.Umask(0)
+		return os.Unsetenv(daemonEnv)
+	}
+}
+
+// respawn starts a detached copy of the program for the given Daemonize
+// stage, then exits the current process.
+func respawn(stage string, setsid bool) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the executable: %w", err)
+	}
+	null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+	}
+	defer null.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = MergeEnv(os.Environ(), []string{daemonEnv + "=" + stage})
+	cmd.Dir = "/"
+	cmd.Stdin = null
+	cmd.Stdout = null
+	cmd.Stderr = null
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: setsid}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the daemon: %w", err)
+	}
+
+	logEvent("daemon_stage_started", fields{"stage": stage, "child_pid": cmd.Process.Pid},
+		"PID %d. Daemonize stage %s started as PID %d.", pid, stage, cmd.Process.Pid)
+	exitFn(0)
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package proc
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestDaemonize_FinalStage(t *testing.T) {
+	t.Setenv(daemonEnv, "2")
+	old := syscall.Umask(0o022)
+	defer syscall.Umask(old)
+
+	if err := Daemonize(); err != nil {
+		t.Fatalf("Daemonize returned error: %v", err)
+	}
+	if _, ok := os.LookupEnv(daemonEnv); ok {
+		t.Fatalf("%s should be removed in the daemon", daemonEnv)
+	}
+	if got := syscall.Umask(0o022); got != 0 {
+		t.Fatalf("umask = %#o, want 0", got)
+	}
+}
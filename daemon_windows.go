@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package proc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Daemonize is not supported on Windows, which has no notion of sessions or
+// controlling terminals; run the program as a Windows service instead. It
+// always returns an error wrapping errors.ErrUnsupported.
+func Daemonize() error {
+	return fmt.Errorf("daemonize: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package proc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDaemonize_Unsupported(t *testing.T) {
+	if err := Daemonize(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("Daemonize should be unsupported on Windows, got: %v", err)
+	}
+}
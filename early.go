@@ -0,0 +1,84 @@
+package proc
+
+import (
+	"os"
+	"slices"
+)
+
+// earlyBufferSize bounds the number of signals queued by BufferEarlySignals.
+const earlyBufferSize = 16
+
+var (
+	// early reports whether BufferEarlySignals was called. Like the
+	// variables below, it is protected by lock.
+	early bool
+	// earlyWatched holds the signal numbers BufferEarlySignals registered
+	// with the OS, re-registered when signal handling is restarted.
+	earlyWatched []int
+	// earlyQueue holds the queued signals in arrival order.
+	earlyQueue []os.Signal
+	// earlyDone marks the signal numbers that got a listener, which ends
+	// queueing for them.
+	earlyDone [numSig]bool
+)
+
+// BufferEarlySignals closes the startup window in which a signal arrives
+// after the OS handler is installed but before the application registered
+// its listeners, e.g. an early SIGHUP meant for a reload handler. sigs are
+// registered with the OS right away, so they are caught instead of taking
+// their default action.
+//
+// From then on, a signal that is not a shutdown signal and arrives while
+// nobody listens for it is queued rather than dropped, as long as no
+// listener was ever registered for it. When the first listener for such a
+// signal is registered, the signals queued for it are dispatched again in
+// arrival order, asynchronously and shortly after the registration. Later
+// signals are dispatched as usual. At most 16 signals are queued; further
+// ones are dropped.
+//
+// Call it early in main, before the listeners are registered.
+func BufferEarlySignals(sigs ...os.Signal) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	early = true
+	for _, sig := range sigs {
+		if n := signum(sig); n > -1 && !slices.Contains(earlyWatched, n) {
+			earlyWatched = append(earlyWatched, n)
+			watch(n)
+		}
+	}
+}
+
+// queueEarly queues sig, numbered n, if it was received before anyone
+// listened for it and BufferEarlySignals is in effect, and reports whether
+// it did. The caller must hold lock.
+func queueEarly(sig os.Signal, n int) bool {
+	if !early || earlyDone[n] || slices.ContainsFunc(lns, func(l *listener) bool { return l.sig == n }) {
+		return false
+	}
+	if len(earlyQueue) >= earlyBufferSize {
+		logEvent("early_signal_dropped", fields{"signal": sig}, "PID %d. Dropped early %v, buffer full.", pid, sig)
+		return true
+	}
+	earlyQueue = append(earlyQueue, sig)
+	return true
+}
+
+// takeEarly ends queueing for the signal numbered n, which got a listener,
+// and returns the signals queued for it. The caller must hold lock.
+func takeEarly(n int) []os.Signal {
+	if !early || earlyDone[n] {
+		return nil
+	}
+	earlyDone[n] = true
+	var sigs []os.Signal
+	earlyQueue = slices.DeleteFunc(earlyQueue, func(sig os.Signal) bool {
+		if signum(sig) == n {
+			sigs = append(sigs, sig)
+			return true
+		}
+		return false
+	})
+	return sigs
+}
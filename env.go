@@ -0,0 +1,60 @@
+package proc
+
+import (
+	"runtime"
+	"strings"
+)
+
+// MergeEnv merges two environment lists of "key=value" entries. Entries in
+// override replace entries in base that have the same key, and duplicate
+// keys within either list are collapsed with the last one winning. The
+// result keeps the position at which each key first appeared.
+//
+// Keys are compared case-insensitively on Windows, matching how the
+// platform treats environment variable names.
+func MergeEnv(base, override []string) []string {
+	out := make([]string, 0, len(base)+len(override))
+	idx := make(map[string]int, len(base)+len(override))
+	for _, env := range [][]string{base, override} {
+		for _, kv := range env {
+			k := envKey(kv)
+			if i, ok := idx[k]; ok {
+				out[i] = kv
+				continue
+			}
+			idx[k] = len(out)
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+// envKey returns the normalized key of a "key=value" environment entry.
+func envKey(kv string) string {
+	// Windows has hidden per-drive variables such as "=C:=C:\dir", whose
+	// key starts with '=', so the search for the separator skips it.
+	i := 0
+	if strings.HasPrefix(kv, "=") {
+		i = 1
+	}
+	if j := strings.IndexByte(kv[i:], '='); j >= 0 {
+		kv = kv[:i+j]
+	}
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(kv)
+	}
+	return kv
+}
+
+// lookupEnv returns the value of key in an environment list of "key=value"
+// entries, the last one winning like in MergeEnv.
+func lookupEnv(env []string, key string) (string, bool) {
+	key = envKey(key)
+	for i := len(env) - 1; i >= 0; i-- {
+		if envKey(env[i]) == key {
+			_, v, _ := strings.Cut(env[i][1:], "=")
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,53 @@
+package proc
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeEnv_OverrideWins(t *testing.T) {
+	got := MergeEnv(
+		[]string{"A=1", "B=2", "C=3"},
+		[]string{"B=20", "D=4"},
+	)
+	want := []string{"A=1", "B=20", "C=3", "D=4"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("MergeEnv = %q, want %q", got, want)
+	}
+}
+
+func TestMergeEnv_DedupesWithinLists(t *testing.T) {
+	got := MergeEnv(
+		[]string{"A=1", "A=2"},
+		[]string{"B=1", "B=2"},
+	)
+	want := []string{"A=2", "B=2"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("MergeEnv = %q, want %q", got, want)
+	}
+}
+
+func TestMergeEnv_Empty(t *testing.T) {
+	if got := MergeEnv(nil, nil); len(got) != 0 {
+		t.Fatalf("MergeEnv(nil, nil) = %q, want empty", got)
+	}
+	got := MergeEnv(nil, []string{"A=1"})
+	if !slices.Equal(got, []string{"A=1"}) {
+		t.Fatalf("MergeEnv = %q, want [A=1]", got)
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	cases := map[string]string{
+		"FOO=bar":     "FOO",
+		"FOO=a=b":     "FOO",
+		"FOO=":        "FOO",
+		"FOO":         "FOO",
+		"=C:=C:\\dir": "=C:",
+	}
+	for kv, want := range cases {
+		if got := envKey(kv); got != want {
+			t.Fatalf("envKey(%q) = %q, want %q", kv, got, want)
+		}
+	}
+}
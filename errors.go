@@ -0,0 +1,87 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+var (
+	// ErrStartFailed is returned by Exec when the command could not be
+	// started, e.g. because the executable does not exist.
+	ErrStartFailed = errors.New("failed to start the app")
+	// ErrCommandNotFound is returned by Exec, together with ErrStartFailed,
+	// when ExecOptions.VerifyCommand is set and the command is not an
+	// executable file, e.g. because the tool is not installed.
+	ErrCommandNotFound = errors.New("command not found")
+	// ErrExitNonZero matches, via errors.Is, any *ExitError returned by Exec.
+	ErrExitNonZero = errors.New("app exited with non-zero status")
+	// ErrTimeout is returned by Exec when the command was stopped because
+	// its deadline, either ExecOptions.Timeout or the context's, expired.
+	ErrTimeout = errors.New("app timed out")
+	// ErrStartupTimeout is returned by Exec, together with ErrTimeout, when
+	// the command wrote no output within ExecOptions.StartupTimeout.
+	ErrStartupTimeout = errors.New("app produced no output in time")
+	// ErrCancelled is returned by Exec when the command was stopped because
+	// its context was cancelled.
+	ErrCancelled = errors.New("app cancelled")
+	// ErrShuttingDown is returned when an operation was abandoned because
+	// the process began shutting down, e.g. wrapped by the ErrCancelled
+	// error of a command stopped by Shutdown.
+	ErrShuttingDown = errors.New("process is shutting down")
+	// ErrShutdownCancelled is returned by Shutdown when CancelShutdown
+	// aborted it during its force-quit delay.
+	ErrShutdownCancelled = errors.New("shutdown cancelled")
+	// ErrOutputTooLarge is returned by Exec when the command wrote more
+	// output than ExecOptions.MaxOutputBytes allows.
+	ErrOutputTooLarge = errors.New("app output too large")
+)
+
+// ExitError is returned by Exec when the command ran to completion but
+// exited with a status not listed in ExecOptions.SuccessCodes, i.e. a
+// non-zero status by default. Use errors.As to retrieve the exit code.
+type ExitError struct {
+	// Code is the exit code reported by the command, -1 if it was
+	// terminated by a signal.
+	Code int
+	// Signal is the signal that terminated the command, e.g. SIGKILL from
+	// the OOM killer or SIGSEGV from a crash, or 0 if it exited on its own.
+	// Always 0 on Windows, where processes are not terminated by signals.
+	Signal syscall.Signal
+	// Err is the underlying error, typically an *exec.ExitError.
+	Err error
+	// Stderr holds the tail of the command's standard error output when
+	// Exec captured it, i.e. when ExecOptions.Stderr was not set and
+	// StderrMode is Pipe, or Inherit with the output piped anyway, e.g.
+	// through OutputPrefix. It is included in the error message.
+	Stderr string
+	// MemoryLimit is ExecOptions.MemoryLimitBytes, or 0 if unset. The
+	// kernel does not report running out of it, so a set limit is named in
+	// the error message as the likely culprit.
+	MemoryLimit int64
+}
+
+// Error implements the error interface.
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("app exited with code %d", e.Code)
+	if e.Signal != 0 {
+		msg = fmt.Sprintf("app terminated by signal %v", e.Signal)
+	}
+	if e.MemoryLimit > 0 {
+		msg += fmt.Sprintf(" (memory limited to %d bytes)", e.MemoryLimit)
+	}
+	if e.Stderr != "" {
+		return msg + ": " + e.Stderr
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrExitNonZero.
+func (e *ExitError) Is(target error) bool {
+	return target == ErrExitNonZero
+}
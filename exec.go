@@ -1,33 +1,97 @@
 package proc
 
 import (
+	"bytes"
 	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
+// CancelPipeEnv is the environment variable holding the file descriptor of
+// the pipe passed to a command run with ExecOptions.CancelPipe.
+const CancelPipeEnv = "PROC_CANCEL_FD"
+
 // ExecOptions configures command execution parameters.
 type ExecOptions struct {
 	// WorkDir specifies the working directory for the command.
-	// If empty, defaults to the current process's working directory.
+	// If empty, defaults to the current process's working directory, or to
+	// "/" when Chroot is set. With Chroot, it is relative to the new root.
 	WorkDir string
 	// Timeout specifies the maximum duration for command execution.
 	// If > 0, a timeout context will be created.
 	Timeout time.Duration
+	// Deadline, if not zero, is the wall-clock instant by which the command
+	// must have finished, e.g. to share a time budget among several commands.
+	// If Timeout is set too, whichever expires first applies.
+	Deadline time.Time
+	// StartupTimeout, if > 0, bounds how long the command may run before
+	// writing its first output to Stdout or Stderr. If it stays silent for
+	// longer, it is stopped and Exec returns ErrStartupTimeout; once output
+	// appears, only Timeout applies.
+	StartupTimeout time.Duration
 	// Env specifies additional environment variables to pass to the command.
-	// These are appended to the current process's environment.
+	// They are merged into the current process's environment, replacing
+	// variables of the same name.
 	Env []string
-	// Stdin specifies the standard input for the command.
+	// EnvFromContext, if set, is called with the context passed to Exec when
+	// the command starts, and returns additional environment variables, e.g.
+	// to propagate a trace ID as TRACE_ID=... They override both the
+	// current process's environment and Env.
+	EnvFromContext func(ctx context.Context) []string
+	// EnvFunc, if set, receives the fully merged environment, i.e. the
+	// current process's environment overridden by Env and then by
+	// EnvFromContext, and returns the environment the command gets, e.g.
+	// to filter out secrets. Returning nil or an empty slice starts the
+	// command with an empty environment. When CancelPipe is set,
+	// CancelPipeEnv is appended after EnvFunc has run, as the descriptor
+	// number is only known then, so EnvFunc cannot see or remove it.
+	EnvFunc func(base []string) []string
+	// Stdin specifies the standard input for the command. A reader that
+	// is not an *os.File, e.g. an interactive one, does not keep Exec from
+	// returning once the command has exited or been cancelled; it is then
+	// no longer read, although a read already in progress still completes
+	// in the background.
 	Stdin io.Reader
+	// StdinBytes is fed to the command as its standard input, a shorthand
+	// for Stdin: bytes.NewReader(b). Mutually exclusive with Stdin and
+	// StdinString.
+	StdinBytes []byte
+	// StdinString is like StdinBytes for a string.
+	StdinString string
 	// Stdout specifies the standard output for the command.
+	// If nil, defaults to os.Stdout.
 	Stdout io.Writer
 	// Stderr specifies the standard error output for the command.
+	// If nil, defaults to os.Stderr, and when the output goes through a
+	// pipe anyway, e.g. with OutputPrefix, its tail is also kept for the
+	// message of an ExitError.
 	Stderr io.Writer
+	// StdoutMode selects where the standard output goes: Inherit (the
+	// default, honoring Stdout), Pipe to capture it into ExecResult.Stdout,
+	// Discard, or Custom(w). Must be Inherit when Stdout is set.
+	StdoutMode Stream
+	// StderrMode is like StdoutMode for the standard error output.
+	StderrMode Stream
+	// TeeOutput makes the streams captured with Pipe also go to the
+	// corresponding stream of the current process as they are produced,
+	// like tee, e.g. to show a build log while grepping it afterwards.
+	// Requires StdoutMode or StderrMode to be Pipe.
+	TeeOutput bool
+	// OutputPrefix, if not empty, is prepended to every line the command
+	// writes, e.g. "[worker-1] ", to tell apart the output of several
+	// commands sharing a destination. Output captured with Pipe is kept
+	// as is.
+	OutputPrefix string
 	// Command specifies the command to execute.
 	Command string
 	// Args specifies the command arguments.
@@ -35,25 +99,329 @@ type ExecOptions struct {
 	// TTK (Time To Kill) specifies the delay between sending interrupt signal
 	// and kill signal during command cancellation.
 	TTK time.Duration
+	// CancelPipe passes the read end of a pipe to the command, whose file
+	// descriptor is in the CancelPipeEnv environment variable. The write
+	// end is closed, so reads return end of file, as soon as the context
+	// of the command is done, e.g. on cancellation, timeout or shutdown.
+	// This lets a cooperating child poll or select on the descriptor to
+	// learn about cancellation instantly and without signals. Unix only;
+	// on Windows a true value makes Exec fail.
+	CancelPipe bool
+	// PidFile, if not empty, is the path of a file the PID of the command
+	// is written to right after it started, e.g. for tools that signal a
+	// supervised child, and which is removed once it has exited. The file
+	// is replaced atomically, so readers never see it partially written.
+	// Nothing is written if the command fails to start, and Exec stops the
+	// command and fails if the file cannot be written.
+	PidFile string
+	// VerifyCommand makes Exec check that Command is an executable file
+	// before doing anything else with it, looking a bare name up in the
+	// PATH of the environment the command gets rather than of the current
+	// process, and fail with ErrCommandNotFound otherwise. Relative paths,
+	// in Command or in PATH, are taken relative to WorkDir. The binary
+	// found is the one run. This tells "not
+	// installed" apart from "ran but failed". Not supported with Chroot.
+	VerifyCommand bool
+	// OnCancel, if set, replaces the interrupt as the first step of stopping
+	// a cancelled command, e.g. to send SIGTERM or write "quit" to a
+	// control socket; the process group is still killed if the command is
+	// running TTK later. If it returns an error, which is logged, the
+	// interrupt is sent instead. Without a TTK it runs right before the
+	// kill.
+	OnCancel func(cmd *exec.Cmd) error
+	// Logger receives the diagnostics of this command, e.g. "app exited
+	// successfully", so concurrent commands can log to different places.
+	// If nil, the package-level Logger is used. Set it to io.Discard to
+	// silence a single command while the package-level Logger stays on.
+	Logger io.Writer
+	// WarmupDelay, if > 0, is how long the command is given to settle after
+	// it started before it is reported as started through OnStart and
+	// OnStartCtx, for commands that need a fixed time before they are
+	// usable but offer nothing to probe. If the context is done during the
+	// warmup, the command is stopped as usual and the callbacks are skipped.
+	WarmupDelay time.Duration
 	// OnStart is a callback function invoked after the command starts.
 	OnStart func(cmd *exec.Cmd)
+	// OnStartCtx is like OnStart but also receives the context the command
+	// runs under, which carries its deadline and is cancelled once the
+	// command has finished, e.g. to end a tracing span with it. It is
+	// invoked after OnStart when both are set.
+	OnStartCtx func(ctx context.Context, cmd *exec.Cmd)
+	// CgroupPath specifies a cgroup v2 directory (e.g. /sys/fs/cgroup/runner)
+	// the command is placed into at clone time. Linux only; on other
+	// platforms a non-empty value makes Exec fail.
+	CgroupPath string
+	// ResetSignals makes the command start with the default disposition for
+	// signals the current process ignores (e.g. SIGHUP under nohup), which
+	// it would otherwise inherit. Unix only; ignored on Windows.
+	ResetSignals bool
+	// SuccessCodes lists the exit codes treated as success, e.g. []int{0, 1}
+	// for grep, where 1 means "no match". Any other exit code makes Exec
+	// return an *ExitError. If empty, defaults to []int{0}.
+	SuccessCodes []int
+	// MaxOutputBytes caps the combined number of bytes the command may
+	// write to Stdout and Stderr. If > 0 and the cap is exceeded, the
+	// command is killed and Exec returns ErrOutputTooLarge.
+	MaxOutputBytes int64
+	// CPUAffinity pins the command to the listed CPUs right after it starts,
+	// before OnStart runs. Linux only; on other platforms a non-empty value
+	// makes Exec fail.
+	CPUAffinity []int
+	// MemoryLimitBytes, if > 0, caps the address space of the command
+	// (RLIMIT_AS) right after it starts, so allocations beyond it fail and
+	// a leaking command dies instead of exhausting the host's memory. As
+	// the limit covers virtual memory, it must leave headroom above the
+	// expected usage. A failure of a limited command mentions the limit.
+	// Linux only; on other platforms a positive value makes Exec fail.
+	MemoryLimitBytes int64
+	// Chroot, if not empty, changes the root directory of the command to
+	// this path before it is executed, e.g. to sandbox untrusted tools.
+	// Command must then be a path within the new root, as a bare name is
+	// looked up in the PATH of the current process. Requires root or
+	// CAP_SYS_CHROOT. Unix only; on Windows a non-empty value makes Exec
+	// fail.
+	Chroot string
+	// Namespaces selects new Linux namespaces the command is started in,
+	// for lightweight container-style isolation. Requires root or
+	// CAP_SYS_ADMIN unless User is set. Linux only; on other platforms
+	// requesting any namespace makes Exec fail.
+	Namespaces Namespaces
+	// KillOnParentDeath makes the kernel send SIGKILL to the command when
+	// the current process dies, even if it is killed without a chance to
+	// clean up, so a hard-killed supervisor leaves no orphans
+	// (PR_SET_PDEATHSIG). The signal actually follows the OS thread that
+	// started the command: it is also sent if that thread exits while the
+	// process lives on, which Go only does for threads locked with
+	// runtime.LockOSThread that exit locked. Linux only; a no-op elsewhere.
+	KillOnParentDeath bool
+	// Umask, if not nil, is the file mode creation mask the command starts
+	// with, e.g. 0o077 so the files it creates are private, instead of the
+	// umask of the current process. As Go cannot set it for the child
+	// alone, the umask of the whole process is switched while the command
+	// is being started and restored right after, so files created
+	// concurrently by the current process may get it too. Unix only; on
+	// Windows a non-nil value makes Exec fail.
+	Umask *int
+}
+
+// Namespaces selects the Linux namespaces a command run by Exec gets its
+// own copy of. The zero value shares all namespaces with the current
+// process.
+type Namespaces struct {
+	// UTS isolates the hostname and domain name.
+	UTS bool
+	// PID gives the command its own process IDs, starting at 1.
+	PID bool
+	// Mount isolates the mount table.
+	Mount bool
+	// Net gives the command its own network stack, with only a loopback
+	// interface, which is down.
+	Net bool
+	// IPC isolates System V IPC objects and POSIX message queues.
+	IPC bool
+	// User creates a user namespace in which the current user and group are
+	// mapped to root, which lets an unprivileged process create the other
+	// namespaces, if the system permits unprivileged user namespaces.
+	User bool
+}
+
+// Validate checks the options for misconfigurations that would otherwise
+// surface as confusing failures deep inside exec. All problems found are
+// reported together in a single joined error; nil means the options are valid.
+func (opts ExecOptions) Validate() error {
+	var errs []error
+	if opts.Command == "" {
+		errs = append(errs, errors.New("Command must not be empty"))
+	}
+	if opts.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("Timeout must not be negative, got %v", opts.Timeout))
+	}
+	if opts.StartupTimeout < 0 {
+		errs = append(errs, fmt.Errorf("StartupTimeout must not be negative, got %v", opts.StartupTimeout))
+	}
+	if opts.WarmupDelay < 0 {
+		errs = append(errs, fmt.Errorf("WarmupDelay must not be negative, got %v", opts.WarmupDelay))
+	}
+	if opts.MemoryLimitBytes < 0 {
+		errs = append(errs, fmt.Errorf("MemoryLimitBytes must not be negative, got %d", opts.MemoryLimitBytes))
+	}
+	if opts.Umask != nil && (*opts.Umask < 0 || *opts.Umask > 0o777) {
+		errs = append(errs, fmt.Errorf("Umask must be within 0 and 0o777, got %#o", *opts.Umask))
+	}
+	if opts.MaxOutputBytes < 0 {
+		errs = append(errs, fmt.Errorf("MaxOutputBytes must not be negative, got %d", opts.MaxOutputBytes))
+	}
+	if opts.Stdout != nil && opts.StdoutMode.mode != streamInherit {
+		errs = append(errs, errors.New("Stdout and StdoutMode are mutually exclusive"))
+	}
+	if opts.Stderr != nil && opts.StderrMode.mode != streamInherit {
+		errs = append(errs, errors.New("Stderr and StderrMode are mutually exclusive"))
+	}
+	inputs := 0
+	for _, set := range []bool{opts.Stdin != nil, opts.StdinBytes != nil, opts.StdinString != ""} {
+		if set {
+			inputs++
+		}
+	}
+	if inputs > 1 {
+		errs = append(errs, errors.New("Stdin, StdinBytes and StdinString are mutually exclusive"))
+	}
+	if opts.VerifyCommand && opts.Chroot != "" {
+		errs = append(errs, errors.New("VerifyCommand and Chroot are mutually exclusive"))
+	}
+	if opts.TeeOutput && opts.StdoutMode.mode != streamPipe && opts.StderrMode.mode != streamPipe {
+		errs = append(errs, errors.New("TeeOutput requires StdoutMode or StderrMode to be Pipe"))
+	}
+	if opts.StdoutMode.mode == streamCustom && opts.StdoutMode.w == nil {
+		errs = append(errs, errors.New("StdoutMode must not be Custom(nil)"))
+	}
+	if opts.StderrMode.mode == streamCustom && opts.StderrMode.w == nil {
+		errs = append(errs, errors.New("StderrMode must not be Custom(nil)"))
+	}
+	if opts.TTK < 0 {
+		errs = append(errs, fmt.Errorf("TTK must not be negative, got %v", opts.TTK))
+	}
+	return errors.Join(errs...)
+}
+
+// ExecResult describes a command run by Run.
+type ExecResult struct {
+	// Stdout holds the standard output captured when StdoutMode is Pipe.
+	Stdout []byte
+	// Stderr holds the standard error captured when StderrMode is Pipe.
+	Stderr []byte
+	// TimedOut reports whether the command was stopped because its
+	// deadline, either ExecOptions.Timeout or the context's, expired.
+	TimedOut bool
+	// ForceKilled reports whether a stopped command had to be killed,
+	// either right away because TTK is 0 or because it was still running
+	// TTK after the interrupt. It is false for a command that exited on
+	// its own after the interrupt.
+	ForceKilled bool
+	// ProcessState describes how the command ended: its exit code, whether
+	// it was terminated by a signal, and its user and system CPU time.
+	ProcessState *os.ProcessState
+	// TerminatedBy is the signal that terminated the command, telling a
+	// crash (SIGSEGV) or a kill (SIGKILL) apart from a non-zero exit, or 0
+	// if it exited on its own. Always 0 on Windows, where processes are not
+	// terminated by signals.
+	TerminatedBy syscall.Signal
 }
 
 // Exec executes a command with the given context and options.
 // It supports timeout, graceful shutdown with configurable kill delay,
 // and proper process group management to prevent zombie processes.
 //
+// When the current process begins shutting down (see Shutdown), a running
+// command is stopped like on cancellation, honoring TTK, and the shutdown
+// waits for it to exit, before or after notifying the listeners depending
+// on SetShutdownOrder. Exec then returns an error
+// matching both ErrCancelled and ErrShuttingDown.
+//
 // References:
 // - https://github.com/gouravkrosx/golang-cmd-exit-demo?ref=hackernoon.com
 // - https://keploy.io/blog/technology/managing-go-processes
 func Exec(ctx context.Context, opts ExecOptions) error {
+	_, err := Run(ctx, opts)
+	return err
+}
+
+// Start is like Exec but returns as soon as the command has started. The
+// returned reader yields the command's combined standard output and error
+// as they are produced, e.g. for streaming them into an HTTP response, and
+// reports io.EOF once the command exited successfully, or the error Exec
+// would have returned otherwise. Closing the reader stops the command if it
+// is still running and waits for it to exit.
+//
+// The output streams are owned by the reader, so Stdout, Stderr, StdoutMode
+// and StderrMode must not be set. Errors preventing the command from
+// starting are returned directly.
+func Start(ctx context.Context, opts ExecOptions) (io.ReadCloser, error) {
+	if opts.Stdout != nil || opts.Stderr != nil || opts.StdoutMode.mode != streamInherit || opts.StderrMode.mode != streamInherit {
+		return nil, errors.New("invalid exec options: Start owns the output, Stdout, Stderr and their modes must not be set")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	opts.StdoutMode = Custom(pw)
+	opts.StderrMode = Custom(pw)
+
+	started := make(chan struct{})
+	onStart := opts.OnStart
+	opts.OnStart = func(cmd *exec.Cmd) {
+		close(started)
+		if onStart != nil {
+			onStart(cmd)
+		}
+	}
+
+	done := make(chan struct{})
+	var err error
+	go func() {
+		defer close(done)
+		_, err = Run(ctx, opts)
+		pw.CloseWithError(err)
+	}()
+
+	select {
+	case <-started:
+	case <-done:
+		select {
+		case <-started:
+		default:
+			cancel()
+			return nil, err
+		}
+	}
+	return &startReader{PipeReader: pr, cancel: cancel, done: done}, nil
+}
+
+// startReader is the reader returned by Start.
+type startReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Close stops the command if it is still running and waits for it to exit.
+func (r *startReader) Close() error {
+	r.PipeReader.Close()
+	r.cancel()
+	<-r.done
+	return nil
+}
+
+// Run is like Exec but also returns an ExecResult describing the command.
+// The result is non-nil whenever the command was started, even if Run
+// returns an error, so output captured before a failure is available.
+func Run(ctx context.Context, opts ExecOptions) (*ExecResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid exec options: %w", err)
+	}
+
 	if opts.WorkDir == "" {
 		opts.WorkDir = workdir
+		if opts.Chroot != "" {
+			// the working directory of the current process is meaningless
+			// inside the new root
+			opts.WorkDir = "/"
+		}
 	}
 
-	var cancel context.CancelFunc
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// bound the command by the earlier of Timeout and Deadline
+	deadline := opts.Deadline
 	if opts.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		if d := time.Now().Add(opts.Timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	if !deadline.IsZero() {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithDeadline(ctx, deadline)
+		defer cancelTimeout()
 	}
 
 	// Run the app as the user who invoked sudo
@@ -67,14 +435,71 @@ func Exec(ctx context.Context, opts ExecOptions) error {
 	// }
 	cmd := exec.CommandContext(ctx, opts.Command, opts.Args...)
 	cmd.Dir = cmp.Or(opts.WorkDir, workdir)
-	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.Env = MergeEnv(os.Environ(), opts.Env)
+	if opts.EnvFromContext != nil {
+		cmd.Env = MergeEnv(cmd.Env, opts.EnvFromContext(ctx))
+	}
+	if opts.EnvFunc != nil {
+		cmd.Env = opts.EnvFunc(cmd.Env)
+		if cmd.Env == nil {
+			// a nil Env would make the command inherit the environment
+			cmd.Env = []string{}
+		}
+	}
+
+	if opts.VerifyCommand {
+		path, err := verifyCommand(opts.Command, cmd.Dir, cmd.Env)
+		if err != nil {
+			return nil, err
+		}
+		// run the binary verified, which exec.CommandContext may have
+		// looked up differently, in the PATH of the current process
+		cmd.Path, cmd.Err = path, nil
+	}
+
+	if opts.CancelPipe {
+		r, w, err := setCancelPipe(cmd)
+		if err != nil {
+			return nil, err
+		}
+		// the command keeps its own copy of the read end; closing the
+		// write end is the cancellation notice
+		defer r.Close()
+		stop := context.AfterFunc(ctx, func() { _ = w.Close() })
+		defer func() {
+			stop()
+			_ = w.Close()
+		}()
+	}
 
-	// Set the cancel function for the command
+	// Set the cancel function for the command: with a TTK, ask the process
+	// group to stop, via OnCancel if set, and kill it if it is still
+	// running once TTK elapsed, otherwise kill it now.
+	var forceKilled atomic.Bool
+	var killTimer atomic.Pointer[time.Timer]
+	onCancel := func() error {
+		err := opts.OnCancel(cmd)
+		if err != nil {
+			opts.logEvent("cancel_failed", fields{"command": opts.Command, "error": err}, "PID %d. OnCancel failed: %v.", cmd.Process.Pid, err)
+		}
+		return err
+	}
 	cmd.Cancel = func() error {
-		if cancel != nil {
-			cancel()
+		if opts.TTK > 0 {
+			killTimer.Store(time.AfterFunc(opts.TTK, func() {
+				forceKilled.Store(true)
+				_ = terminate(cmd)
+			}))
+			if opts.OnCancel != nil && onCancel() == nil {
+				return nil
+			}
+			return interrupt(cmd)
 		}
-		return nil
+		if opts.OnCancel != nil {
+			_ = onCancel()
+		}
+		forceKilled.Store(true)
+		return terminate(cmd)
 	}
 
 	// wait after sending the interrupt signal, before sending the kill signal
@@ -84,36 +509,345 @@ func Exec(ctx context.Context, opts ExecOptions) error {
 
 	SetSysProcAttribute(cmd)
 
-	// Sets the input of the command
-	if opts.Stdin != nil {
-		cmd.Stdin = opts.Stdin
+	if opts.Chroot != "" {
+		if err := setChroot(cmd, opts.Chroot); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Namespaces != (Namespaces{}) {
+		if err := setNamespaces(cmd, opts.Namespaces); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.KillOnParentDeath {
+		setParentDeathSignal(cmd)
+	}
+
+	if opts.CgroupPath != "" {
+		release, err := setCgroup(cmd, opts.CgroupPath)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	// Sets the input of the command. A reader other than a file is fed by
+	// our own goroutine rather than by cmd: Wait would otherwise wait for
+	// its copy, blocked reading an interactive reader, forever.
+	var stdinPipe io.WriteCloser
+	switch {
+	case opts.Stdin != nil:
+		if f, ok := opts.Stdin.(*os.File); ok {
+			cmd.Stdin = f
+		} else {
+			w, err := cmd.StdinPipe()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrStartFailed, err)
+			}
+			stdinPipe = w
+		}
+	case opts.StdinBytes != nil:
+		cmd.Stdin = bytes.NewReader(opts.StdinBytes)
+	case opts.StdinString != "":
+		cmd.Stdin = strings.NewReader(opts.StdinString)
 	}
 
 	// Sets the output of the command
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = opts.StdoutMode.writer(opts.Stdout, os.Stdout, &stdout)
+	cmd.Stderr = opts.StderrMode.writer(opts.Stderr, os.Stderr, &stderr)
+	if opts.TeeOutput {
+		if opts.StdoutMode.mode == streamPipe {
+			cmd.Stdout = io.MultiWriter(cmd.Stdout, os.Stdout)
+		}
+		if opts.StderrMode.mode == streamPipe {
+			cmd.Stderr = io.MultiWriter(cmd.Stderr, os.Stderr)
+		}
+	}
+
+	if opts.OutputPrefix != "" {
+		prefixOutput(cmd, opts.OutputPrefix, opts.StdoutMode.mode != streamPipe, opts.StderrMode.mode != streamPipe)
+	}
+
+	if opts.MaxOutputBytes > 0 {
+		limitOutput(cmd, opts.MaxOutputBytes, func() { cancel(ErrOutputTooLarge) })
+	}
+
+	var sawOutput atomic.Bool
+	if opts.StartupTimeout > 0 {
+		watchOutput(cmd, func() { sawOutput.Store(true) })
+	}
 
-	err := cmd.Start()
+	// Keep the end of an inherited standard error for the ExitError, so a
+	// failure explains itself, if it goes through a pipe anyway. Piped
+	// output is already at hand, and a plain *os.File is passed to the
+	// command as is: turning it into a pipe would take the terminal away
+	// from it and make Wait block until its descendants close it.
+	var stderrTail *tailBuffer
+	if _, isFile := cmd.Stderr.(*os.File); opts.Stderr == nil && opts.StderrMode.mode == streamInherit && !isFile {
+		stderrTail = &tailBuffer{max: stderrTailSize}
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, stderrTail)
+	}
+
+	restoreUmask := func() {}
+	if opts.Umask != nil {
+		var err error
+		if restoreUmask, err = setUmask(*opts.Umask); err != nil {
+			return nil, err
+		}
+	}
+	restoreSignals := func() {}
+	if opts.ResetSignals {
+		restoreSignals = resetSignals()
+	}
+	release, err := startWaited(cmd)
+	started := time.Now()
+	restoreSignals()
+	restoreUmask()
 	if err != nil {
-		return fmt.Errorf("failed to start the app: %w", err)
+		if errors.Is(err, syscall.EPERM) {
+			switch {
+			case opts.Chroot != "":
+				return nil, fmt.Errorf("%w: chroot to %q requires root or CAP_SYS_CHROOT: %w", ErrStartFailed, opts.Chroot, err)
+			case opts.Namespaces != (Namespaces{}):
+				return nil, fmt.Errorf("%w: creating namespaces requires root, CAP_SYS_ADMIN or Namespaces.User: %w", ErrStartFailed, err)
+			}
+		}
+		return nil, fmt.Errorf("%w: %w", ErrStartFailed, err)
+	}
+	defer release()
+
+	if stdinPipe != nil {
+		go feedStdin(stdinPipe, opts.Stdin)
+	}
+
+	if len(opts.CPUAffinity) > 0 {
+		if err := setAffinity(cmd.Process.Pid, opts.CPUAffinity); err != nil {
+			_ = terminate(cmd)
+			_ = cmd.Wait()
+			return nil, err
+		}
+	}
+
+	if opts.MemoryLimitBytes > 0 {
+		if err := setMemoryLimit(cmd.Process.Pid, opts.MemoryLimitBytes); err != nil {
+			_ = terminate(cmd)
+			_ = cmd.Wait()
+			return nil, err
+		}
+	}
+
+	if opts.PidFile != "" {
+		if err := writePidFile(opts.PidFile, cmd.Process.Pid); err != nil {
+			_ = terminate(cmd)
+			_ = cmd.Wait()
+			return nil, err
+		}
+		defer os.Remove(opts.PidFile)
+	}
+
+	untrack := trackChild(ChildInfo{
+		Pid:       cmd.Process.Pid,
+		Command:   opts.Command,
+		Args:      opts.Args,
+		StartedAt: started,
+	}, func() error {
+		forceKilled.Store(true)
+		return terminate(cmd)
+	})
+
+	// Stop the command when the process begins shutting down, and hold the
+	// shutdown until the command has exited so it is not orphaned.
+	exited := make(chan struct{})
+	unhook := onShutdown(func() {
+		cancel(ErrShuttingDown)
+		<-exited
+	})
+
+	if opts.StartupTimeout > 0 {
+		startup := time.AfterFunc(opts.StartupTimeout, func() {
+			if !sawOutput.Load() {
+				cancel(ErrStartupTimeout)
+			}
+		})
+		defer startup.Stop()
 	}
 
-	if opts.OnStart != nil {
+	warmedUp := true
+	if opts.WarmupDelay > 0 {
+		warmup := time.NewTimer(opts.WarmupDelay)
+		select {
+		case <-warmup.C:
+		case <-ctx.Done():
+			warmup.Stop()
+			warmedUp = false
+		}
+	}
+
+	if warmedUp && opts.OnStart != nil {
 		opts.OnStart(cmd)
 	}
+	if warmedUp && opts.OnStartCtx != nil {
+		opts.OnStartCtx(ctx, cmd)
+	}
 
 	err = cmd.Wait()
+	untrack()
+	close(exited)
+	unhook()
+	if t := killTimer.Load(); t != nil {
+		t.Stop()
+	}
+
+	res := &ExecResult{ProcessState: cmd.ProcessState}
+	res.TerminatedBy, _ = terminatedBy(cmd.ProcessState)
+	if opts.StdoutMode.mode == streamPipe {
+		res.Stdout = stdout.Bytes()
+	}
+	if opts.StderrMode.mode == streamPipe {
+		res.Stderr = stderr.Bytes()
+	}
+	err = waitError(ctx, err, opts)
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		ee.Signal = res.TerminatedBy
+		switch {
+		case stderrTail != nil:
+			ee.Stderr = tail(stderrTail.buf, stderrTailSize)
+		case opts.StderrMode.mode == streamPipe:
+			ee.Stderr = tail(stderr.Bytes(), stderrTailSize)
+		}
+	}
+	if errors.Is(err, ErrTimeout) || errors.Is(err, ErrCancelled) {
+		res.TimedOut = errors.Is(err, ErrTimeout)
+		// WaitDelay may have killed the process before our own timer did
+		res.ForceKilled = forceKilled.Load() || res.TerminatedBy == syscall.SIGKILL
+	}
+	return res, err
+}
+
+// writePidFile writes pid to the file at path, replacing it atomically by
+// renaming a temporary file in the same directory.
+func writePidFile(path string, pid int) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write pid file: %w", err)
+	}
+	_, err = fmt.Fprintln(f, pid)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("failed to write pid file: %w", err)
+	}
+	return nil
+}
+
+// verifyCommand checks that name is an executable file for a command run
+// in dir with the environment env, and returns its absolute path: a name
+// containing a path separator is taken relative to dir, any other is looked
+// up in the PATH of env, whose relative entries are taken relative to dir
+// too. An empty dir stands for the current directory.
+func verifyCommand(name, dir string, env []string) (string, error) {
+	resolve := func(path string) (string, error) {
+		path, err := exec.LookPath(path)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(path)
+	}
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		path := name
+		if dir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		path, err := resolve(path)
+		if err != nil {
+			return "", fmt.Errorf("%w, %w: %w", ErrStartFailed, ErrCommandNotFound, err)
+		}
+		return path, nil
+	}
+	path, _ := lookupEnv(env, "PATH")
+	for _, d := range filepath.SplitList(path) {
+		if d == "" {
+			continue
+		}
+		if dir != "" && !filepath.IsAbs(d) {
+			d = filepath.Join(dir, d)
+		}
+		if found, err := resolve(filepath.Join(d, name)); err == nil {
+			return found, nil
+		}
+	}
+	return "", fmt.Errorf("%w, %w: %q not found in PATH %q", ErrStartFailed, ErrCommandNotFound, name, path)
+}
+
+// feedStdin copies r to the standard input w of a command, and closes w at
+// the end of r. Once the command has been waited for, Wait has closed w,
+// so the copy stops at the next write: a read of r already in progress
+// cannot be interrupted, but r is never read again.
+func feedStdin(w io.WriteCloser, r io.Reader) {
+	_, _ = io.Copy(w, r)
+	_ = w.Close()
+}
+
+// logEvent writes a diagnostic event about the command to opts.Logger, or
+// to the package-level Logger if it is nil, see the package-level logEvent.
+func (opts ExecOptions) logEvent(event string, f fields, format string, args ...any) {
+	if opts.Logger == nil {
+		logEvent(event, f, format, args...)
+		return
+	}
+	if opts.Logger != io.Discard {
+		writeEvent(opts.Logger, event, f, format, args...)
+	}
+}
+
+// waitError classifies the outcome of waiting for a command into the
+// errors documented for Exec.
+func waitError(ctx context.Context, err error, opts ExecOptions) error {
+	if errors.Is(context.Cause(ctx), ErrOutputTooLarge) {
+		return fmt.Errorf("%w: more than %d bytes", ErrOutputTooLarge, opts.MaxOutputBytes)
+	}
+	if errors.Is(context.Cause(ctx), ErrStartupTimeout) {
+		return fmt.Errorf("%w, %w: no output within %v", ErrTimeout, ErrStartupTimeout, opts.StartupTimeout)
+	}
+	if errors.Is(context.Cause(ctx), ErrShuttingDown) {
+		return fmt.Errorf("%w, error while waiting for the app to exit: %w", ErrCancelled, ErrShuttingDown)
+	}
 	select {
 	case <-ctx.Done():
-		if ctxerr := ctx.Err(); ctxerr != nil {
-			return fmt.Errorf("context cancelled, error while waiting for the app to exit: %w", ctxerr)
+		ctxerr := ctx.Err()
+		if errors.Is(ctxerr, context.DeadlineExceeded) {
+			return fmt.Errorf("%w, error while waiting for the app to exit: %w", ErrTimeout, ctxerr)
 		}
-		return err
+		return fmt.Errorf("%w, error while waiting for the app to exit: %w", ErrCancelled, ctxerr)
 	default:
-		if err != nil {
+		var ee *exec.ExitError
+		if err != nil && !errors.As(err, &ee) {
 			return fmt.Errorf("unexpected error while waiting for the app to exit: %w", err)
 		}
-		log.Println("app exited successfully")
+		code := 0
+		if ee != nil {
+			code = ee.ExitCode()
+		}
+		success := opts.SuccessCodes
+		if len(success) == 0 {
+			success = []int{0}
+		}
+		if !slices.Contains(success, code) {
+			return &ExitError{Code: code, Err: err, MemoryLimit: opts.MemoryLimitBytes}
+		}
+		opts.logEvent("exec_exited", fields{"command": opts.Command}, "app exited successfully")
 		return nil
 	}
 }
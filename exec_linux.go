@@ -0,0 +1,104 @@
+//go:build linux
+// +build linux
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// setCgroup arranges for the command to be placed into the cgroup v2
+// directory at path when it is cloned, using SysProcAttr.CgroupFD
+// (CLONE_INTO_CGROUP). The returned function closes the cgroup directory
+// descriptor and must be called once the command has been started.
+func setCgroup(cmd *exec.Cmd, path string) (func(), error) {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cgroup %q: %w", path, err)
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = fd
+	return func() { _ = syscall.Close(fd) }, nil
+}
+
+// setParentDeathSignal makes the kernel kill the command once the thread
+// that started it exits, using SysProcAttr.Pdeathsig.
+func setParentDeathSignal(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+}
+
+// setNamespaces makes the command start in the namespaces selected by ns,
+// using SysProcAttr.Cloneflags. A user namespace maps the current user and
+// group to root.
+func setNamespaces(cmd *exec.Cmd, ns Namespaces) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+	for _, f := range []struct {
+		set  bool
+		flag uintptr
+	}{
+		{ns.UTS, syscall.CLONE_NEWUTS},
+		{ns.PID, syscall.CLONE_NEWPID},
+		{ns.Mount, syscall.CLONE_NEWNS},
+		{ns.Net, syscall.CLONE_NEWNET},
+		{ns.IPC, syscall.CLONE_NEWIPC},
+		{ns.User, syscall.CLONE_NEWUSER},
+	} {
+		if f.set {
+			attr.Cloneflags |= f.flag
+		}
+	}
+	if ns.User {
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+		// an unprivileged process may only map its group once setgroups
+		// is denied
+		attr.GidMappingsEnableSetgroups = false
+	}
+	return nil
+}
+
+// maxCPUs is the number of CPUs a cpuAffinity mask can describe.
+const maxCPUs = 1024
+
+// setAffinity restricts the process pid to run on the given CPUs using
+// sched_setaffinity(2).
+func setAffinity(pid int, cpus []int) error {
+	var mask [maxCPUs / 64]uint64
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= maxCPUs {
+			return fmt.Errorf("failed to set CPU affinity: CPU %d out of range [0, %d)", cpu, maxCPUs)
+		}
+		mask[cpu/64] |= 1 << (cpu % 64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY,
+		uintptr(pid), unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return fmt.Errorf("failed to set CPU affinity to %v: %w", cpus, errno)
+	}
+	return nil
+}
+
+// setMemoryLimit caps the address space of the process pid at limit bytes
+// using prlimit(2) with RLIMIT_AS, so allocations beyond it fail.
+func setMemoryLimit(pid int, limit int64) error {
+	rlim := syscall.Rlimit{Cur: uint64(limit), Max: uint64(limit)}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64,
+		uintptr(pid), syscall.RLIMIT_AS, uintptr(unsafe.Pointer(&rlim)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to limit memory to %d bytes: %w", limit, errno)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+//go:build linux
+
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExec_CgroupPath_Missing(t *testing.T) {
+	err := Exec(context.Background(), ExecOptions{
+		Command:    "sh",
+		Args:       []string{"-c", "true"},
+		CgroupPath: filepath.Join(t.TempDir(), "missing"),
+		Timeout:    2 * time.Second,
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing cgroup directory")
+	}
+	if !strings.Contains(err.Error(), "cgroup") {
+		t.Fatalf("Expected cgroup error, got: %v", err)
+	}
+}
+
+func TestExec_CgroupPath_Placement(t *testing.T) {
+	// Place the child into the cgroup v2 group we are already in, which
+	// avoids needing to create (and clean up) a new group.
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		t.Skipf("cannot read own cgroup: %v", err)
+	}
+	var rel string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if after, ok := strings.CutPrefix(line, "0::"); ok {
+			rel = after
+		}
+	}
+	dir := filepath.Join("/sys/fs/cgroup", rel)
+	if _, err := os.Stat(filepath.Join(dir, "cgroup.procs")); rel == "" || err != nil {
+		t.Skip("cgroup v2 unified hierarchy not available")
+	}
+
+	var got string
+	err = Exec(context.Background(), ExecOptions{
+		Command:    "sh",
+		Args:       []string{"-c", "sleep 1"},
+		CgroupPath: dir,
+		Timeout:    2 * time.Second,
+		OnStart: func(cmd *exec.Cmd) {
+			b, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(cmd.Process.Pid), "cgroup"))
+			got = string(b)
+		},
+	})
+	if err != nil {
+		t.Skipf("cgroup placement not permitted here: %v", err)
+	}
+	if !strings.Contains(got, "0::"+rel) {
+		t.Fatalf("child cgroup = %q, want it to contain %q", got, "0::"+rel)
+	}
+}
+
+func TestExec_CPUAffinity(t *testing.T) {
+	// Pin to the first CPU we are allowed to run on ourselves.
+	own, err := cpusAllowed("self")
+	if err != nil {
+		t.Skipf("cannot read own CPU affinity: %v", err)
+	}
+	cpu, err := strconv.Atoi(strings.FieldsFunc(own, func(r rune) bool { return r == ',' || r == '-' })[0])
+	if err != nil {
+		t.Fatalf("cannot parse Cpus_allowed_list %q: %v", own, err)
+	}
+
+	var got string
+	err = Exec(context.Background(), ExecOptions{
+		Command:     "sleep",
+		Args:        []string{"0.1"},
+		CPUAffinity: []int{cpu},
+		Timeout:     2 * time.Second,
+		OnStart: func(cmd *exec.Cmd) {
+			got, _ = cpusAllowed(strconv.Itoa(cmd.Process.Pid))
+		},
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got != strconv.Itoa(cpu) {
+		t.Fatalf("child Cpus_allowed_list = %q, want %q", got, strconv.Itoa(cpu))
+	}
+}
+
+func TestExec_CPUAffinity_Invalid(t *testing.T) {
+	err := Exec(context.Background(), ExecOptions{
+		Command:     "sleep",
+		Args:        []string{"5"},
+		CPUAffinity: []int{maxCPUs},
+		Timeout:     2 * time.Second,
+	})
+	if err == nil || !strings.Contains(err.Error(), "CPU affinity") {
+		t.Fatalf("Expected CPU affinity error, got: %v", err)
+	}
+}
+
+// cpusAllowed returns the Cpus_allowed_list of the process pid, which may
+// be "self".
+func cpusAllowed(pid string) (string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", pid, "status"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if after, ok := strings.CutPrefix(line, "Cpus_allowed_list:"); ok {
+			return strings.TrimSpace(after), nil
+		}
+	}
+	return "", errors.New("Cpus_allowed_list not found")
+}
+
+func TestExec_Namespaces(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Run(context.Background(), ExecOptions{
+		Command:    "sh",
+		Args:       []string{"-c", "hostname sandbox && hostname && echo $$"},
+		Namespaces: Namespaces{UTS: true, PID: true},
+		StdoutMode: Pipe,
+		Timeout:    5 * time.Second,
+	})
+	if errors.Is(err, syscall.EPERM) {
+		t.Skipf("namespaces not permitted: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := strings.Fields(string(res.Stdout)); len(got) != 2 || got[0] != "sandbox" || got[1] != "1" {
+		t.Fatalf("output = %q, want the new hostname and PID 1", res.Stdout)
+	}
+	if now, _ := os.Hostname(); now != host {
+		t.Fatalf("hostname of the current process changed to %q", now)
+	}
+}
+
+func TestExec_MemoryLimitBytes(t *testing.T) {
+	const limit = 256 << 20
+	var got string
+	err := Exec(context.Background(), ExecOptions{
+		Command:          "sleep",
+		Args:             []string{"0.1"},
+		MemoryLimitBytes: limit,
+		Timeout:          2 * time.Second,
+		OnStart: func(cmd *exec.Cmd) {
+			data, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(cmd.Process.Pid), "limits"))
+			for _, line := range strings.Split(string(data), "\n") {
+				if strings.HasPrefix(line, "Max address space") {
+					got = strings.Fields(line)[3]
+				}
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got != strconv.Itoa(limit) {
+		t.Fatalf("child address space limit = %q, want %d", got, limit)
+	}
+
+	err = Exec(context.Background(), ExecOptions{
+		Command:          "sh",
+		Args:             []string{"-c", "exit 2"},
+		MemoryLimitBytes: limit,
+		StderrMode:       Discard,
+	})
+	var ee *ExitError
+	if !errors.As(err, &ee) || ee.Code != 2 || !strings.Contains(err.Error(), "memory limited") {
+		t.Fatalf("Expected *ExitError mentioning the limit, got: %v", err)
+	}
+
+	// the signal and the stderr tail are reported along with the limit
+	err = Exec(context.Background(), ExecOptions{
+		Command:          "sh",
+		Args:             []string{"-c", "echo out of memory >&2; kill -KILL $$"},
+		MemoryLimitBytes: limit,
+		StderrMode:       Pipe,
+	})
+	want := fmt.Sprintf("app terminated by signal killed (memory limited to %d bytes): out of memory", limit)
+	if !errors.As(err, &ee) || ee.Signal != syscall.SIGKILL || err.Error() != want {
+		t.Fatalf("Expected %q, got: %v", want, err)
+	}
+}
+
+func TestExec_KillOnParentDeath(t *testing.T) {
+	for _, on := range []bool{false, true} {
+		var got syscall.Signal
+		err := Exec(context.Background(), ExecOptions{
+			Command:           "true",
+			KillOnParentDeath: on,
+			OnStart: func(cmd *exec.Cmd) {
+				if cmd.SysProcAttr != nil {
+					got = cmd.SysProcAttr.Pdeathsig
+				}
+			},
+		})
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		want := syscall.Signal(0)
+		if on {
+			want = syscall.SIGKILL
+		}
+		if got != want {
+			t.Fatalf("KillOnParentDeath %v: Pdeathsig = %v, want %v", on, got, want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !linux
+// +build !linux
+
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// setCgroup reports that cgroup placement is unavailable, as cgroups
+// only exist on Linux.
+func setCgroup(_ *exec.Cmd, path string) (func(), error) {
+	return nil, fmt.Errorf("cannot place command into cgroup %q: %w", path, errors.ErrUnsupported)
+}
+
+// setAffinity reports that CPU pinning is unavailable, as it is only
+// implemented on Linux.
+func setAffinity(_ int, cpus []int) error {
+	return fmt.Errorf("cannot set CPU affinity to %v: %w", cpus, errors.ErrUnsupported)
+}
+
+// setNamespaces reports that namespaces are unavailable, as they only
+// exist on Linux.
+func setNamespaces(_ *exec.Cmd, ns Namespaces) error {
+	return fmt.Errorf("cannot create namespaces %+v: %w", ns, errors.ErrUnsupported)
+}
+
+// setParentDeathSignal does nothing, as only Linux can signal a child
+// when its parent dies.
+func setParentDeathSignal(_ *exec.Cmd) {}
+
+// setMemoryLimit reports that memory limits are unavailable, as they are
+// only implemented on Linux.
+func setMemoryLimit(_ int, limit int64) error {
+	return fmt.Errorf("cannot limit memory to %d bytes: %w", limit, errors.ErrUnsupported)
+}
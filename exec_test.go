@@ -1,11 +1,17 @@
 package proc
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -66,6 +72,9 @@ func TestExec_WithStdinStdout(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Exec with custom IO failed: %v", err)
 	}
+	if !strings.Contains(stdout.String(), "ok") {
+		t.Fatalf("Stdout should receive the command output, got: %q", stdout.String())
+	}
 }
 
 func TestExec_ContextCancellation(t *testing.T) {
@@ -148,6 +157,69 @@ func TestExec_InvalidCommand(t *testing.T) {
 	}
 }
 
+func TestExec_VerifyCommand(t *testing.T) {
+	err := Exec(context.Background(), ExecOptions{Command: "nonexistent-command-12345", VerifyCommand: true})
+	if !errors.Is(err, ErrCommandNotFound) || !errors.Is(err, ErrStartFailed) {
+		t.Fatalf("Expected ErrCommandNotFound and ErrStartFailed, got: %v", err)
+	}
+
+	cmd, args := trivialEcho()
+	if err := Exec(context.Background(), ExecOptions{Command: cmd, Args: args, VerifyCommand: true, StdoutMode: Discard}); err != nil {
+		t.Fatalf("Exec of an installed command failed: %v", err)
+	}
+
+	// the PATH of the command's environment is searched, not ours
+	err = Exec(context.Background(), ExecOptions{
+		Command:       cmd,
+		Args:          args,
+		Env:           []string{"PATH=" + t.TempDir()},
+		VerifyCommand: true,
+	})
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Fatalf("Expected ErrCommandNotFound with an empty PATH, got: %v", err)
+	}
+}
+
+func TestExec_PidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "child.pid")
+	var content string
+	var childPid int
+	cmd, args := trivialEcho()
+	err := Exec(context.Background(), ExecOptions{
+		Command:    cmd,
+		Args:       args,
+		PidFile:    path,
+		StdoutMode: Discard,
+		OnStart: func(c *exec.Cmd) {
+			childPid = c.Process.Pid
+			data, _ := os.ReadFile(path)
+			content = string(data)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if content != strconv.Itoa(childPid)+"\n" {
+		t.Fatalf("pid file held %q while running, want %d", content, childPid)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("pid file should be removed once the command exited, stat: %v", err)
+	}
+
+	err = Exec(context.Background(), ExecOptions{Command: "nonexistent-command-12345", PidFile: path})
+	if !errors.Is(err, ErrStartFailed) {
+		t.Fatalf("Expected ErrStartFailed, got: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("no pid file should be written when the start fails, stat: %v", err)
+	}
+
+	err = Exec(context.Background(), ExecOptions{Command: cmd, Args: args, PidFile: filepath.Join(path, "missing", "child.pid")})
+	if err == nil || !strings.Contains(err.Error(), "pid file") {
+		t.Fatalf("Expected a pid file error, got: %v", err)
+	}
+}
+
 func TestExec_CommandFailure(t *testing.T) {
 	// Run a command that exits with non-zero status
 	var cmd string
@@ -182,3 +254,361 @@ func sleepCmd(d time.Duration) (string, []string) {
 	}
 	return "sh", []string{"-c", "sleep " + strconv.Itoa(sec)}
 }
+
+func TestExecOptions_Validate(t *testing.T) {
+	badUmask := 0o1000
+	cases := []struct {
+		name string
+		opts ExecOptions
+		want []string
+	}{
+		{"valid", ExecOptions{Command: "sh"}, nil},
+		{"empty command", ExecOptions{}, []string{"Command must not be empty"}},
+		{"negative timeout", ExecOptions{Command: "sh", Timeout: -time.Second}, []string{"Timeout must not be negative"}},
+		{"negative ttk", ExecOptions{Command: "sh", TTK: -time.Second}, []string{"TTK must not be negative"}},
+		{"stdin and StdinString", ExecOptions{Command: "sh", Stdin: strings.NewReader("a"), StdinString: "b"}, []string{"mutually exclusive"}},
+		{"TeeOutput without Pipe", ExecOptions{Command: "sh", TeeOutput: true}, []string{"TeeOutput requires"}},
+		{"umask out of range", ExecOptions{Command: "sh", Umask: &badUmask}, []string{"Umask must be within"}},
+		{"StdinBytes and StdinString", ExecOptions{Command: "sh", StdinBytes: []byte("a"), StdinString: "b"}, []string{"mutually exclusive"}},
+		{
+			"aggregated",
+			ExecOptions{Timeout: -1, TTK: -1},
+			[]string{"Command must not be empty", "Timeout must not be negative", "TTK must not be negative"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate returned unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate should fail with %q", tc.want)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Fatalf("Validate error %q should contain %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestExec_InvalidOptions(t *testing.T) {
+	err := Exec(context.Background(), ExecOptions{Timeout: -time.Second})
+	if err == nil {
+		t.Fatal("Expected error for invalid options")
+	}
+	if !strings.Contains(err.Error(), "invalid exec options") {
+		t.Fatalf("Expected validation error, got: %v", err)
+	}
+}
+
+func TestExec_TypedErrors(t *testing.T) {
+	t.Run("start failed", func(t *testing.T) {
+		err := Exec(context.Background(), ExecOptions{Command: "nonexistent-command-12345"})
+		if !errors.Is(err, ErrStartFailed) {
+			t.Fatalf("Expected ErrStartFailed, got: %v", err)
+		}
+	})
+
+	t.Run("exit non-zero", func(t *testing.T) {
+		cmd, args := "sh", []string{"-c", "exit 3"}
+		if isWindows() {
+			cmd, args = "cmd", []string{"/C", "exit", "3"}
+		}
+		err := Exec(context.Background(), ExecOptions{Command: cmd, Args: args, Timeout: 2 * time.Second})
+		if !errors.Is(err, ErrExitNonZero) {
+			t.Fatalf("Expected ErrExitNonZero, got: %v", err)
+		}
+		var ee *ExitError
+		if !errors.As(err, &ee) || ee.Code != 3 {
+			t.Fatalf("Expected *ExitError with code 3, got: %v", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cmd, args := sleepCmd(2 * time.Second)
+		err := Exec(context.Background(), ExecOptions{Command: cmd, Args: args, Timeout: 50 * time.Millisecond, TTK: 50 * time.Millisecond})
+		if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected ErrTimeout, got: %v", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		cmd, args := sleepCmd(2 * time.Second)
+		err := Exec(ctx, ExecOptions{Command: cmd, Args: args, TTK: 50 * time.Millisecond})
+		if !errors.Is(err, ErrCancelled) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected ErrCancelled, got: %v", err)
+		}
+	})
+}
+
+func TestRun_StreamModes(t *testing.T) {
+	cmd, args := "sh", []string{"-c", "echo out; echo err >&2"}
+	if isWindows() {
+		cmd, args = "cmd", []string{"/C", "echo out& echo err 1>&2"}
+	}
+
+	t.Run("pipe stdout, custom stderr", func(t *testing.T) {
+		var stderr strings.Builder
+		res, err := Run(context.Background(), ExecOptions{
+			Command:    cmd,
+			Args:       args,
+			StdoutMode: Pipe,
+			StderrMode: Custom(&stderr),
+			Timeout:    2 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if got := strings.TrimSpace(string(res.Stdout)); got != "out" {
+			t.Fatalf("captured stdout = %q, want %q", got, "out")
+		}
+		if res.Stderr != nil {
+			t.Fatalf("stderr should not be captured, got %q", res.Stderr)
+		}
+		if got := strings.TrimSpace(stderr.String()); got != "err" {
+			t.Fatalf("custom stderr = %q, want %q", got, "err")
+		}
+	})
+
+	t.Run("discard stdout, pipe stderr", func(t *testing.T) {
+		res, err := Run(context.Background(), ExecOptions{
+			Command:    cmd,
+			Args:       args,
+			StdoutMode: Discard,
+			StderrMode: Pipe,
+			Timeout:    2 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if res.Stdout != nil {
+			t.Fatalf("discarded stdout should not be captured, got %q", res.Stdout)
+		}
+		if got := strings.TrimSpace(string(res.Stderr)); got != "err" {
+			t.Fatalf("captured stderr = %q, want %q", got, "err")
+		}
+	})
+}
+
+func TestExecOptions_Validate_StreamModes(t *testing.T) {
+	var buf strings.Builder
+	err := ExecOptions{Command: "sh", Stdout: &buf, StdoutMode: Pipe}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("Stdout with StdoutMode should be rejected, got: %v", err)
+	}
+	err = ExecOptions{Command: "sh", StderrMode: Custom(nil)}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "StderrMode must not be Custom(nil)") {
+		t.Fatalf("Custom(nil) should be rejected, got: %v", err)
+	}
+	if err := (ExecOptions{Command: "sh", Stdout: &buf, StderrMode: Pipe}).Validate(); err != nil {
+		t.Fatalf("Stdout with a StderrMode is valid, got: %v", err)
+	}
+}
+
+func TestExec_SuccessCodes(t *testing.T) {
+	exitCmd := func(code int) (string, []string) {
+		if isWindows() {
+			return "cmd", []string{"/C", "exit", strconv.Itoa(code)}
+		}
+		return "sh", []string{"-c", "exit " + strconv.Itoa(code)}
+	}
+
+	cases := []struct {
+		name    string
+		code    int
+		success []int
+		wantErr bool
+	}{
+		{"default zero", 0, nil, false},
+		{"default non-zero", 1, nil, true},
+		{"listed non-zero", 1, []int{0, 1}, false},
+		{"unlisted non-zero", 2, []int{0, 1}, true},
+		{"zero not listed", 0, []int{1}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, args := exitCmd(tc.code)
+			err := Exec(context.Background(), ExecOptions{
+				Command:      cmd,
+				Args:         args,
+				SuccessCodes: tc.success,
+				Timeout:      2 * time.Second,
+			})
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("Exec should succeed, got: %v", err)
+				}
+				return
+			}
+			var ee *ExitError
+			if !errors.As(err, &ee) || ee.Code != tc.code {
+				t.Fatalf("Expected *ExitError with code %d, got: %v", tc.code, err)
+			}
+		})
+	}
+}
+
+func TestExec_StoppedOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		cmd, args := sleepCmd(5 * time.Second)
+		result <- Exec(context.Background(), ExecOptions{
+			Command: cmd,
+			Args:    args,
+			OnStart: func(*exec.Cmd) { close(started) },
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("command did not start")
+	}
+
+	// the shutdown must not proceed before the command has been stopped
+	notifyShutdown(syscall.SIGTERM)
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrShuttingDown) || !errors.Is(err, ErrCancelled) {
+			t.Fatalf("Expected ErrCancelled and ErrShuttingDown, got: %v", err)
+		}
+	default:
+		t.Fatal("Exec should have returned before the shutdown notification completed")
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tb := &tailBuffer{max: 8}
+	io.WriteString(tb, "first line\n")
+	io.WriteString(tb, "héllo\n")
+	if got := tail(tb.buf, 6); got != "éllo" {
+		t.Fatalf("tail = %q, want %q", got, "éllo")
+	}
+	if got := tail(tb.buf, 5); got != "llo" {
+		t.Fatalf("tail cutting a rune = %q, want %q", got, "llo")
+	}
+	if len(tb.buf) != 8 {
+		t.Fatalf("tailBuffer kept %d bytes, want 8", len(tb.buf))
+	}
+}
+
+func TestPrefixWriter_PartialLines(t *testing.T) {
+	var buf bytes.Buffer
+	pw := &prefixWriter{w: &buf, prefix: []byte("[a] ")}
+	for _, s := range []string{"hel", "lo\nwor", "ld\n", "\n", "tail"} {
+		if n, err := io.WriteString(pw, s); n != len(s) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	want := "[a] hello\n[a] world\n[a] \n[a] tail"
+	if got := buf.String(); got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestExec_Logger(t *testing.T) {
+	old := Logger
+	var global bytes.Buffer
+	Logger = &global
+	defer func() { Logger = old }()
+
+	var own bytes.Buffer
+	cmd, args := trivialEcho()
+	if err := Exec(context.Background(), ExecOptions{Command: cmd, Args: args, Logger: &own, StdoutMode: Discard}); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if !strings.Contains(own.String(), "app exited successfully") {
+		t.Fatalf("per-command Logger got %q, want the success message", own.String())
+	}
+	if global.Len() != 0 {
+		t.Fatalf("global Logger got %q, want nothing", global.String())
+	}
+
+	// without a per-command Logger the global one is used
+	if err := Exec(context.Background(), ExecOptions{Command: cmd, Args: args, StdoutMode: Discard}); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if !strings.Contains(global.String(), "app exited successfully") {
+		t.Fatalf("global Logger got %q, want the success message", global.String())
+	}
+
+	// io.Discard silences the command without touching the global one
+	global.Reset()
+	if err := Exec(context.Background(), ExecOptions{Command: cmd, Args: args, Logger: io.Discard, StdoutMode: Discard}); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if global.Len() != 0 {
+		t.Fatalf("global Logger got %q with a discarding per-command Logger, want nothing", global.String())
+	}
+}
+
+func TestExec_OnStartCtx(t *testing.T) {
+	var calls []string
+	var runCtx context.Context
+	cmd, args := trivialEcho()
+	err := Exec(context.Background(), ExecOptions{
+		Command:    cmd,
+		Args:       args,
+		StdoutMode: Discard,
+		Timeout:    time.Minute,
+		OnStart:    func(*exec.Cmd) { calls = append(calls, "OnStart") },
+		OnStartCtx: func(ctx context.Context, cmd *exec.Cmd) {
+			calls = append(calls, "OnStartCtx")
+			runCtx = ctx
+			if cmd.Process == nil {
+				t.Error("OnStartCtx called before the command started")
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if strings.Join(calls, ",") != "OnStart,OnStartCtx" {
+		t.Fatalf("callbacks = %v, want OnStart then OnStartCtx", calls)
+	}
+	if _, ok := runCtx.Deadline(); !ok {
+		t.Fatal("context should carry the Timeout deadline")
+	}
+	if runCtx.Err() == nil {
+		t.Fatal("context should be cancelled once the command has finished")
+	}
+}
+
+func TestRunningChildren(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	command, args := sleepCmd(10 * time.Second)
+	pids := make(chan int, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- Exec(ctx, ExecOptions{
+			Command: command,
+			Args:    args,
+			OnStart: func(cmd *exec.Cmd) { pids <- cmd.Process.Pid },
+		})
+	}()
+	pid := <-pids
+
+	i := slices.IndexFunc(RunningChildren(), func(c ChildInfo) bool { return c.Pid == pid })
+	if i == -1 {
+		t.Fatalf("running command %d not listed in %v", pid, RunningChildren())
+	}
+	if c := RunningChildren()[i]; c.Command != command || !slices.Equal(c.Args, args) || c.StartedAt.IsZero() {
+		t.Fatalf("child info = %+v, want command %q %q and a start time", c, command, args)
+	}
+
+	cancel()
+	<-done
+	if slices.ContainsFunc(RunningChildren(), func(c ChildInfo) bool { return c.Pid == pid }) {
+		t.Fatalf("exited command %d still listed", pid)
+	}
+}
@@ -4,7 +4,11 @@
 package proc
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
+	"os/signal"
+	"sync"
 	"syscall"
 )
 
@@ -19,3 +23,115 @@ import (
 func SetSysProcAttribute(cmd *exec.Cmd) {
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
+
+// setChroot makes the command change its root directory to dir before it
+// is executed.
+func setChroot(cmd *exec.Cmd, dir string) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Chroot = dir
+	return nil
+}
+
+// setCancelPipe creates a pipe and appends its read end to cmd.ExtraFiles,
+// which the command inherits as file descriptor 3 plus its index there, and
+// puts that number in CancelPipeEnv. The write end is opened close-on-exec
+// by os.Pipe, so only this process holds it: the caller closes it to
+// notify the command. The caller also closes its own copy of the read end
+// when done, e.g. once the command has been waited for; the command's copy
+// is unaffected.
+func setCancelPipe(cmd *exec.Cmd) (r, w *os.File, err error) {
+	r, w, err = os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create cancel pipe: %w", err)
+	}
+	cmd.ExtraFiles = append(cmd.ExtraFiles, r)
+	// extra files follow stdin, stdout and stderr
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", CancelPipeEnv, 2+len(cmd.ExtraFiles)))
+	return r, w, nil
+}
+
+// interrupt sends SIGINT to the command's process group, so descendants
+// spawned by the command are asked to stop as well.
+func interrupt(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGINT)
+}
+
+// terminate sends SIGKILL to the command's process group.
+func terminate(cmd *exec.Cmd) error {
+	return signalGroup(cmd, syscall.SIGKILL)
+}
+
+// signalGroup sends sig to the process group the command leads, which
+// SetSysProcAttribute created. It falls back to signalling the process
+// alone if the group is gone.
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if err := syscall.Kill(-cmd.Process.Pid, sig); err == nil {
+		return nil
+	}
+	return cmd.Process.Signal(sig)
+}
+
+// terminatedBy returns the signal that terminated the process, if any.
+func terminatedBy(state *os.ProcessState) (syscall.Signal, bool) {
+	if state == nil {
+		return 0, false
+	}
+	ws, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return 0, false
+	}
+	return ws.Signal(), true
+}
+
+// resetSignalsMu serializes resetSignals, which temporarily changes the
+// signal dispositions of the whole process.
+var resetSignalsMu sync.Mutex
+
+// resetSignals makes commands started before the returned function is
+// called begin with the default disposition for the signals the current
+// process ignores, such as SIGHUP under nohup or SIGINT in a background
+// job. SysProcAttr has no field for signal dispositions, and the runtime
+// only resets the handlers it installed itself in the child, while ignored
+// signals stay ignored across exec. So the ignored signals are temporarily
+// handled by Go, discarding deliveries, and ignored again afterwards.
+//
+// The signal mask is not affected: the child starts with the mask the
+// current process was started with.
+func resetSignals() func() {
+	resetSignalsMu.Lock()
+
+	var ignored []os.Signal
+	for sig := syscall.Signal(1); sig < 32; sig++ {
+		if sig != syscall.SIGKILL && sig != syscall.SIGSTOP && signal.Ignored(sig) {
+			ignored = append(ignored, sig)
+		}
+	}
+	if len(ignored) == 0 {
+		return resetSignalsMu.Unlock
+	}
+
+	signal.Notify(make(chan os.Signal, 1), ignored...)
+	return func() {
+		signal.Ignore(ignored...)
+		resetSignalsMu.Unlock()
+	}
+}
+
+// umaskMu serializes setUmask, which temporarily changes the umask of the
+// whole process.
+var umaskMu sync.Mutex
+
+// setUmask makes commands started before the returned function is called
+// begin with the umask mask. SysProcAttr has no field for it, so the umask
+// of the current process, which the child inherits, is switched to mask
+// and switched back by the returned function.
+func setUmask(mask int) (restore func(), err error) {
+	umaskMu.Lock()
+	old := syscall.Umask(mask)
+	return func() {
+		syscall.Umask(old)
+		umaskMu.Unlock()
+	}, nil
+}
@@ -4,8 +4,17 @@
 package proc
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
+	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -49,3 +58,781 @@ func TestExec_ProcessGroupCreated_Unix(t *testing.T) {
 		t.Log("Warning: Could not verify process group ID")
 	}
 }
+
+func TestExec_MaxOutputBytes_Exceeded(t *testing.T) {
+	var out bytes.Buffer
+	start := time.Now()
+	err := Exec(context.Background(), ExecOptions{
+		Command:        "sh",
+		Args:           []string{"-c", "yes"},
+		Stdout:         &out,
+		MaxOutputBytes: 1000,
+		Timeout:        5 * time.Second,
+	})
+	if !errors.Is(err, ErrOutputTooLarge) {
+		t.Fatalf("Expected ErrOutputTooLarge, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("command should be killed promptly, took %v", elapsed)
+	}
+	if out.Len() != 1000 {
+		t.Fatalf("captured %d bytes, want exactly the 1000 byte cap", out.Len())
+	}
+}
+
+func TestExec_MaxOutputBytes_WithinLimit(t *testing.T) {
+	var out bytes.Buffer
+	err := Exec(context.Background(), ExecOptions{
+		Command:        "sh",
+		Args:           []string{"-c", "echo ok; echo err >&2"},
+		Stdout:         &out,
+		Stderr:         &out,
+		MaxOutputBytes: 1000,
+		Timeout:        2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if got := out.String(); !strings.Contains(got, "ok") || !strings.Contains(got, "err") {
+		t.Fatalf("unexpected output: %q", got)
+	}
+}
+
+func TestRun_Timeout_CooperativeChild(t *testing.T) {
+	res, err := Run(context.Background(), ExecOptions{
+		Command: "sh",
+		Args:    []string{"-c", `trap 'kill $!; exit 0' INT; sleep 5 & wait`},
+		Timeout: 100 * time.Millisecond,
+		TTK:     2 * time.Second,
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got: %v", err)
+	}
+	if !res.TimedOut {
+		t.Fatal("TimedOut should be true")
+	}
+	if res.ForceKilled {
+		t.Fatal("a child exiting on interrupt should not be force killed")
+	}
+}
+
+func TestRun_Timeout_UncooperativeChild(t *testing.T) {
+	start := time.Now()
+	res, err := Run(context.Background(), ExecOptions{
+		Command: "sh",
+		Args:    []string{"-c", `trap '' INT; sleep 5 & wait`},
+		Timeout: 100 * time.Millisecond,
+		TTK:     100 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got: %v", err)
+	}
+	if !res.TimedOut || !res.ForceKilled {
+		t.Fatalf("TimedOut=%v ForceKilled=%v, want both true", res.TimedOut, res.ForceKilled)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("child should be killed after TTK, took %v", elapsed)
+	}
+}
+
+func TestRun_Cancel_NoTTKForceKills(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	res, err := Run(ctx, ExecOptions{Command: "sh", Args: []string{"-c", "sleep 5"}})
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("Expected ErrCancelled, got: %v", err)
+	}
+	if res.TimedOut || !res.ForceKilled {
+		t.Fatalf("TimedOut=%v ForceKilled=%v, want false and true", res.TimedOut, res.ForceKilled)
+	}
+}
+
+func TestExec_ResetSignals(t *testing.T) {
+	signal.Ignore(syscall.SIGALRM)
+	defer func() {
+		signal.Reset(syscall.SIGALRM)
+		// Ignore also dropped the package's own registration of SIGALRM,
+		// which later tests listen for
+		lock.Lock()
+		mask &^= 1 << uint(syscall.SIGALRM&31)
+		watch(int(syscall.SIGALRM))
+		lock.Unlock()
+	}()
+
+	// The child sends itself SIGALRM: ignored it survives, reset it dies.
+	opts := ExecOptions{
+		Command: "sh",
+		Args:    []string{"-c", "kill -ALRM $$; exit 0"},
+		Timeout: 2 * time.Second,
+	}
+	if err := Exec(context.Background(), opts); err != nil {
+		t.Fatalf("child should inherit the ignored SIGALRM, got: %v", err)
+	}
+
+	opts.ResetSignals = true
+	if err := Exec(context.Background(), opts); !errors.Is(err, ErrExitNonZero) {
+		t.Fatalf("child should be killed by SIGALRM, got: %v", err)
+	}
+	if !signal.Ignored(syscall.SIGALRM) {
+		t.Fatal("SIGALRM should be ignored again in the parent")
+	}
+}
+
+func TestExec_ExitErrorIncludesStderrTail(t *testing.T) {
+	for _, opts := range []ExecOptions{
+		{StderrMode: Pipe},
+		// piped anyway by the prefix
+		{StderrMode: Inherit, OutputPrefix: "[app] "},
+	} {
+		opts.Command = "sh"
+		opts.Args = []string{"-c", "echo boom >&2; exit 3"}
+		opts.StdoutMode = Discard
+		opts.Timeout = 2 * time.Second
+		err := Exec(context.Background(), opts)
+		var ee *ExitError
+		if !errors.As(err, &ee) {
+			t.Fatalf("Expected *ExitError, got: %v", err)
+		}
+		if ee.Stderr != "boom" || err.Error() != "app exited with code 3: boom" {
+			t.Fatalf("Expected stderr tail in ExitError, got Stderr=%q, message %q", ee.Stderr, err.Error())
+		}
+	}
+
+	// an explicitly set Stderr is left alone
+	var buf bytes.Buffer
+	err := Exec(context.Background(), ExecOptions{
+		Command: "sh",
+		Args:    []string{"-c", "echo boom >&2; exit 3"},
+		Stderr:  &buf,
+		Timeout: 2 * time.Second,
+	})
+	var ee *ExitError
+	if !errors.As(err, &ee) || ee.Stderr != "" {
+		t.Fatalf("Expected *ExitError without stderr tail, got: %v", err)
+	}
+}
+
+func TestExec_InheritedStderrStaysFile(t *testing.T) {
+	var stderr io.Writer
+	start := time.Now()
+	err := Exec(context.Background(), ExecOptions{
+		Command: "sh",
+		Args:    []string{"-c", "sleep 2 &"},
+		OnStart: func(c *exec.Cmd) { stderr = c.Stderr },
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if _, ok := stderr.(*os.File); !ok {
+		t.Fatalf("inherited stderr is a %T, want the *os.File", stderr)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Exec took %v, want it not to wait for the background grandchild", elapsed)
+	}
+}
+
+func TestRun_EnvFromContext(t *testing.T) {
+	type traceKey struct{}
+	ctx := context.WithValue(context.Background(), traceKey{}, "abc123")
+
+	res, err := Run(ctx, ExecOptions{
+		Command:    "sh",
+		Args:       []string{"-c", `printf '%s %s' "$TRACE_ID" "$OTHER"`},
+		Env:        []string{"TRACE_ID=overridden", "OTHER=kept"},
+		StdoutMode: Pipe,
+		EnvFromContext: func(ctx context.Context) []string {
+			return []string{"TRACE_ID=" + ctx.Value(traceKey{}).(string)}
+		},
+		Timeout: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := string(res.Stdout); got != "abc123 kept" {
+		t.Fatalf("stdout = %q, want %q", got, "abc123 kept")
+	}
+}
+
+func TestRun_EnvFunc(t *testing.T) {
+	t.Setenv("PROC_TEST_SECRET", "hunter2")
+
+	var base []string
+	res, err := Run(context.Background(), ExecOptions{
+		Command:    "sh",
+		Args:       []string{"-c", `printf '%s|%s' "$PROC_TEST_SECRET" "$EXTRA"`},
+		Env:        []string{"EXTRA=from-env"},
+		StdoutMode: Pipe,
+		EnvFunc: func(env []string) []string {
+			base = env
+			env = slices.DeleteFunc(env, func(kv string) bool {
+				return strings.HasPrefix(kv, "PROC_TEST_SECRET=")
+			})
+			return MergeEnv(env, []string{"EXTRA=computed"})
+		},
+		Timeout: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !slices.Contains(base, "EXTRA=from-env") {
+		t.Fatalf("EnvFunc should receive the merged environment, got %v", base)
+	}
+	if got := string(res.Stdout); got != "|computed" {
+		t.Fatalf("stdout = %q, want %q", got, "|computed")
+	}
+
+	res, err = Run(context.Background(), ExecOptions{
+		Command:    "env",
+		StdoutMode: Pipe,
+		EnvFunc:    func([]string) []string { return nil },
+		Timeout:    2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Stdout) != 0 {
+		t.Fatalf("EnvFunc returning nil should clear the environment, got %q", res.Stdout)
+	}
+}
+
+func TestShutdown_Order(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	killFn = func(syscall.Signal) error { return nil }
+	oldDelay := TimeToForceQuit()
+	defer SetTimeToForceQuit(oldDelay)
+	SetTimeToForceQuit(0)
+	defer SetShutdownOrder(ChildrenFirst)
+
+	for _, tc := range []struct {
+		order         ShutdownOrder
+		wantChildGone bool
+	}{
+		{ChildrenFirst, true},
+		{ListenersFirst, false},
+	} {
+		SetShutdownOrder(tc.order)
+
+		started := make(chan *exec.Cmd)
+		exited := make(chan struct{})
+		go func() {
+			defer close(exited)
+			Exec(context.Background(), ExecOptions{
+				Command: "sleep",
+				Args:    []string{"5"},
+				OnStart: func(cmd *exec.Cmd) { started <- cmd },
+			})
+		}()
+		cmd := <-started
+
+		var childGone bool
+		id := Once(syscall.SIGTERM, func() {
+			// fails once the child has been waited for
+			childGone = syscall.Kill(cmd.Process.Pid, 0) != nil
+		})
+		Shutdown(syscall.SIGTERM)
+		Cancel(id)
+
+		select {
+		case <-exited:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("order %d: Exec was not stopped by Shutdown", tc.order)
+		}
+		if childGone != tc.wantChildGone {
+			t.Fatalf("order %d: child gone when listeners ran = %v, want %v", tc.order, childGone, tc.wantChildGone)
+		}
+	}
+}
+
+func TestRun_StartupTimeout(t *testing.T) {
+	start := time.Now()
+	res, err := Run(context.Background(), ExecOptions{
+		Command:        "sleep",
+		Args:           []string{"5"},
+		StartupTimeout: 100 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrStartupTimeout) || !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrStartupTimeout and ErrTimeout, got: %v", err)
+	}
+	if !res.TimedOut {
+		t.Fatal("Expected TimedOut to be set")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("silent command ran for %v, want it stopped soon after 100ms", elapsed)
+	}
+
+	// once output appears the command may outlive the startup window
+	err = Exec(context.Background(), ExecOptions{
+		Command:        "sh",
+		Args:           []string{"-c", "echo ready >&2; sleep 0.3"},
+		StderrMode:     Discard,
+		StartupTimeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expected command with early output to succeed, got: %v", err)
+	}
+}
+
+func TestRun_OutputPrefix(t *testing.T) {
+	var out bytes.Buffer
+	res, err := Run(context.Background(), ExecOptions{
+		Command:      "sh",
+		Args:         []string{"-c", "printf 'one\\ntwo\\n'; printf 'raw\\n' >&2"},
+		Stdout:       &out,
+		StderrMode:   Pipe,
+		OutputPrefix: "[job] ",
+		Timeout:      2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := out.String(); got != "[job] one\n[job] two\n" {
+		t.Fatalf("stdout = %q, want prefixed lines", got)
+	}
+	if got := string(res.Stderr); got != "raw\n" {
+		t.Fatalf("piped stderr = %q, want it unprefixed", got)
+	}
+}
+
+func TestRun_Deadline(t *testing.T) {
+	start := time.Now()
+	res, err := Run(context.Background(), ExecOptions{
+		Command:  "sleep",
+		Args:     []string{"5"},
+		Deadline: start.Add(100 * time.Millisecond),
+		Timeout:  time.Minute,
+	})
+	if !errors.Is(err, ErrTimeout) || !res.TimedOut {
+		t.Fatalf("Expected ErrTimeout, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("command ran for %v, want it stopped at the deadline", elapsed)
+	}
+
+	// the earlier Timeout wins over a later Deadline
+	start = time.Now()
+	_, err = Run(context.Background(), ExecOptions{
+		Command:  "sleep",
+		Args:     []string{"5"},
+		Deadline: start.Add(time.Minute),
+		Timeout:  100 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrTimeout) || time.Since(start) > 2*time.Second {
+		t.Fatalf("Expected ErrTimeout soon after 100ms, got %v after %v", err, time.Since(start))
+	}
+}
+
+func TestRun_StdinBytesAndString(t *testing.T) {
+	for _, opts := range []ExecOptions{
+		{StdinBytes: []byte("hello from bytes\n")},
+		{StdinString: "hello from bytes\n"},
+	} {
+		opts.Command = "cat"
+		opts.StdoutMode = Pipe
+		opts.Timeout = 2 * time.Second
+		res, err := Run(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if got := string(res.Stdout); got != "hello from bytes\n" {
+			t.Fatalf("stdout = %q, want the input echoed", got)
+		}
+	}
+}
+
+func TestExec_InteractiveStdinCancelled(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	before := runtime.NumGoroutine()
+
+	// the child waits for input the parent never provides
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- Exec(ctx, ExecOptions{Command: "cat", Stdin: pr, StdoutMode: Discard})
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Exec succeeded, want the cancellation reported")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Exec did not return after its context was cancelled")
+	}
+
+	// the read in progress completes, after which nothing is left running
+	pw.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines running, want at most %d as before Exec", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRun_Umask(t *testing.T) {
+	before := syscall.Umask(0o022)
+	defer syscall.Umask(before)
+	mask := 0o077
+
+	res, err := Run(context.Background(), ExecOptions{
+		Command:    "sh",
+		Args:       []string{"-c", "umask"},
+		Umask:      &mask,
+		StdoutMode: Pipe,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(res.Stdout)); got != "0077" {
+		t.Fatalf("child umask = %s, want 0077", got)
+	}
+	if got := syscall.Umask(0o022); got != 0o022 {
+		t.Fatalf("umask after Run = %#o, want 022 restored", got)
+	}
+}
+
+func TestStart_StreamsCombinedOutput(t *testing.T) {
+	r, err := Start(context.Background(), ExecOptions{
+		Command: "sh",
+		Args:    []string{"-c", "echo out; echo err >&2"},
+		Timeout: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading output failed: %v", err)
+	}
+	if !strings.Contains(string(out), "out\n") || !strings.Contains(string(out), "err\n") {
+		t.Fatalf("output = %q, want both streams", out)
+	}
+
+	r, err = Start(context.Background(), ExecOptions{Command: "sh", Args: []string{"-c", "exit 3"}})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer r.Close()
+	var ee *ExitError
+	if _, err := io.ReadAll(r); !errors.As(err, &ee) || ee.Code != 3 {
+		t.Fatalf("reading output of a failing command = %v, want *ExitError with code 3", err)
+	}
+}
+
+func TestStart_Errors(t *testing.T) {
+	if _, err := Start(context.Background(), ExecOptions{Command: "/nonexistent/cmd"}); !errors.Is(err, ErrStartFailed) {
+		t.Fatalf("Expected ErrStartFailed, got: %v", err)
+	}
+	if _, err := Start(context.Background(), ExecOptions{Command: "true", StdoutMode: Pipe}); err == nil {
+		t.Fatal("Expected an error for an output mode set with Start")
+	}
+}
+
+func TestStart_CloseStopsCommand(t *testing.T) {
+	r, err := Start(context.Background(), ExecOptions{Command: "sleep", Args: []string{"5"}})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	start := time.Now()
+	r.Close()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Close took %v, want the command stopped promptly", elapsed)
+	}
+}
+
+func TestRun_ProcessState(t *testing.T) {
+	res, err := Run(context.Background(), ExecOptions{
+		Command:      "sh",
+		Args:         []string{"-c", "exit 3"},
+		SuccessCodes: []int{0, 3},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.ProcessState == nil || res.ProcessState.ExitCode() != 3 {
+		t.Fatalf("ProcessState = %v, want exit code 3", res.ProcessState)
+	}
+
+	res, err = Run(context.Background(), ExecOptions{
+		Command: "sleep",
+		Args:    []string{"5"},
+		Timeout: 50 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got: %v", err)
+	}
+	ws, ok := res.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() || ws.Signal() != syscall.SIGKILL {
+		t.Fatalf("ProcessState = %v, want terminated by SIGKILL", res.ProcessState)
+	}
+}
+
+func TestExec_Chroot(t *testing.T) {
+	opts := ExecOptions{
+		Command:    "/bin/sh",
+		Args:       []string{"-c", "pwd"},
+		Chroot:     "/",
+		StdoutMode: Pipe,
+	}
+	if syscall.Geteuid() != 0 {
+		_, err := Run(context.Background(), opts)
+		if !errors.Is(err, ErrStartFailed) || !errors.Is(err, syscall.EPERM) {
+			t.Fatalf("unprivileged chroot: err = %v, want ErrStartFailed wrapping EPERM", err)
+		}
+		return
+	}
+
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := strings.TrimSpace(string(res.Stdout)); got != "/" {
+		t.Fatalf("default working directory = %q, want /", got)
+	}
+
+	opts.WorkDir = "/tmp"
+	res, err = Run(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := strings.TrimSpace(string(res.Stdout)); got != "/tmp" {
+		t.Fatalf("working directory = %q, want /tmp", got)
+	}
+}
+
+func TestExec_WarmupDelay(t *testing.T) {
+	var started time.Time
+	begin := time.Now()
+	err := Exec(context.Background(), ExecOptions{
+		Command:     "true",
+		WarmupDelay: 50 * time.Millisecond,
+		OnStart:     func(*exec.Cmd) { started = time.Now() },
+	})
+	if err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if started.IsZero() || started.Sub(begin) < 50*time.Millisecond {
+		t.Fatalf("OnStart ran %v after Exec was called, want after the warmup", started.Sub(begin))
+	}
+}
+
+func TestExec_WarmupDelay_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var called bool
+	begin := time.Now()
+	res, err := Run(ctx, ExecOptions{
+		Command:     "sleep",
+		Args:        []string{"10"},
+		WarmupDelay: 10 * time.Second,
+		OnStart:     func(*exec.Cmd) { called = true },
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Fatalf("Run took %v, the warmup outlived the context", elapsed)
+	}
+	if called {
+		t.Fatal("OnStart should be skipped when the context is done during the warmup")
+	}
+	if res.TerminatedBy != syscall.SIGKILL {
+		t.Fatalf("command should have been killed, state = %v", res.ProcessState)
+	}
+}
+
+func TestRun_TerminatedBy(t *testing.T) {
+	res, err := Run(context.Background(), ExecOptions{
+		Command: "sh",
+		Args:    []string{"-c", "kill -SEGV $$"},
+	})
+	var ee *ExitError
+	if !errors.As(err, &ee) || ee.Signal != syscall.SIGSEGV {
+		t.Fatalf("err = %v, want an *ExitError for SIGSEGV", err)
+	}
+	if !strings.Contains(err.Error(), "terminated by signal") {
+		t.Fatalf("err = %q, want it to name the signal", err)
+	}
+	if res.TerminatedBy != syscall.SIGSEGV {
+		t.Fatalf("TerminatedBy = %v, want SIGSEGV", res.TerminatedBy)
+	}
+
+	res, err = Run(context.Background(), ExecOptions{Command: "sh", Args: []string{"-c", "exit 3"}})
+	if !errors.As(err, &ee) || ee.Signal != 0 || res.TerminatedBy != 0 {
+		t.Fatalf("a non-zero exit reported a signal: err %v, TerminatedBy %v", err, res.TerminatedBy)
+	}
+}
+
+func TestShutdown_KillsChildrenBeforeSelf(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	defer SetTimeToForceQuit(0)
+	defer SetChildKillDelay(DefaultChildKillDelay)
+
+	result := make(chan *ExecResult, 1)
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		// the child ignores the interrupt, so the graceful stop hangs
+		res, _ := Run(context.Background(), ExecOptions{
+			Command: "sh",
+			Args:    []string{"-c", `trap "" INT; echo ready; sleep 10`},
+			TTK:     time.Minute,
+			Stdout:  pw,
+		})
+		result <- res
+	}()
+	if _, err := pr.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("child did not get ready: %v", err)
+	}
+
+	var childGone bool
+	killFn = func(syscall.Signal) error {
+		select {
+		case res := <-result:
+			childGone = res.ForceKilled
+		default:
+		}
+		return nil
+	}
+	SetTimeToForceQuit(50 * time.Millisecond)
+	SetChildKillDelay(500 * time.Millisecond)
+	Shutdown(syscall.SIGTERM)
+	if !childGone {
+		t.Fatal("the child should be force killed and waited for before the process is killed")
+	}
+}
+
+func TestExec_OnCancel(t *testing.T) {
+	run := func(onCancel func(*exec.Cmd) error) (*ExecResult, string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		result := make(chan *ExecResult, 1)
+		go func() {
+			res, _ := Run(ctx, ExecOptions{
+				Command:  "sh",
+				Args:     []string{"-c", `trap "echo got-term; exit 0" TERM; echo ready; while :; do sleep 0.05; done`},
+				TTK:      5 * time.Second,
+				OnCancel: onCancel,
+				Stdout:   pw,
+			})
+			pw.Close()
+			result <- res
+		}()
+		buf := make([]byte, 16)
+		if _, err := pr.Read(buf); err != nil {
+			t.Fatalf("child did not get ready: %v", err)
+		}
+		cancel()
+		out, _ := io.ReadAll(pr)
+		return <-result, string(out)
+	}
+
+	res, out := run(func(cmd *exec.Cmd) error { return cmd.Process.Signal(syscall.SIGTERM) })
+	if !strings.Contains(out, "got-term") || res.ForceKilled {
+		t.Fatalf("output %q, ForceKilled %v: want the command stopped by OnCancel", out, res.ForceKilled)
+	}
+
+	// a failing OnCancel falls back to the interrupt
+	res, out = run(func(*exec.Cmd) error { return errors.New("no control socket") })
+	if strings.Contains(out, "got-term") || res.TerminatedBy != syscall.SIGINT {
+		t.Fatalf("output %q, TerminatedBy %v: want the command interrupted", out, res.TerminatedBy)
+	}
+}
+
+func TestExec_CancelPipe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	result := make(chan *ExecResult, 1)
+	go func() {
+		// the child ignores the interrupt and stops once the pipe closes
+		res, _ := Run(ctx, ExecOptions{
+			Command:    "sh",
+			Args:       []string{"-c", `trap "" INT; echo ready; read x <&"$` + CancelPipeEnv + `"; echo closed`},
+			CancelPipe: true,
+			TTK:        5 * time.Second,
+			Stdout:     pw,
+		})
+		pw.Close()
+		result <- res
+	}()
+	if _, err := pr.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("child did not get ready: %v", err)
+	}
+	cancel()
+	out, _ := io.ReadAll(pr)
+	res := <-result
+	if string(out) != "closed\n" || res.ForceKilled {
+		t.Fatalf("output %q, ForceKilled %v: want the child to see the pipe close", out, res.ForceKilled)
+	}
+}
+
+func TestRun_TeeOutput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	oldStdout := os.Stdout
+	os.Stdout = w
+	res, err := Run(context.Background(), ExecOptions{
+		Command:    "sh",
+		Args:       []string{"-c", "echo out; echo err >&2"},
+		StdoutMode: Pipe,
+		StderrMode: Discard,
+		TeeOutput:  true,
+		Timeout:    2 * time.Second,
+	})
+	os.Stdout = oldStdout
+	w.Close()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	shown, _ := io.ReadAll(r)
+	if string(res.Stdout) != "out\n" || string(shown) != "out\n" {
+		t.Fatalf("captured %q and shown %q, want %q for both", res.Stdout, shown, "out\n")
+	}
+}
+
+func TestVerifyCommand_RelativeToDir(t *testing.T) {
+	td := t.TempDir()
+	if err := os.Mkdir(filepath.Join(td, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tool := filepath.Join(td, "bin", "tool")
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\necho verified\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// relative paths resolve against the command's directory, not ours
+	if path, err := verifyCommand("bin/tool", td, nil); err != nil || path != tool {
+		t.Fatalf("relative command path = %q, %v; want %q", path, err, tool)
+	}
+	if path, err := verifyCommand("tool", td, []string{"PATH=bin"}); err != nil || path != tool {
+		t.Fatalf("relative PATH entry = %q, %v; want %q", path, err, tool)
+	}
+	if _, err := verifyCommand("tool", "", []string{"PATH=bin"}); !errors.Is(err, ErrCommandNotFound) {
+		t.Fatalf("relative PATH entry without a directory = %v, want ErrCommandNotFound", err)
+	}
+
+	// the verified binary is the one that runs, although our PATH lacks it
+	res, err := Run(context.Background(), ExecOptions{
+		Command:       "tool",
+		WorkDir:       td,
+		Env:           []string{"PATH=bin"},
+		VerifyCommand: true,
+		StdoutMode:    Pipe,
+	})
+	if err != nil || strings.TrimSpace(string(res.Stdout)) != "verified" {
+		t.Fatalf("Run = %q, %v; want the verified tool to run", res.Stdout, err)
+	}
+}
@@ -3,10 +3,56 @@
 
 package proc
 
-import "os/exec"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
 
 // SetSysProcAttribute sets the system-specific process attributes for Windows.
 // On Windows, no special process attributes are needed, so this is a no-op.
 func SetSysProcAttribute(cmd *exec.Cmd) {
 	// Do nothing
 }
+
+// setChroot reports that changing the root directory is unavailable, as
+// Windows has no chroot.
+func setChroot(_ *exec.Cmd, dir string) error {
+	return fmt.Errorf("cannot chroot to %q: %w", dir, errors.ErrUnsupported)
+}
+
+// setCancelPipe reports that cancel pipes are unavailable, as Windows
+// cannot pass extra files to a command.
+func setCancelPipe(_ *exec.Cmd) (r, w *os.File, err error) {
+	return nil, nil, fmt.Errorf("cannot pass a cancel pipe: %w", errors.ErrUnsupported)
+}
+
+// interrupt kills the command, since Windows cannot deliver an interrupt to
+// another process.
+func interrupt(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// terminate kills the command.
+func terminate(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// terminatedBy always reports false, as Windows processes are not
+// terminated by signals.
+func terminatedBy(_ *os.ProcessState) (syscall.Signal, bool) {
+	return 0, false
+}
+
+// resetSignals does nothing, as Windows processes do not inherit signal
+// dispositions.
+func resetSignals() func() {
+	return func() {}
+}
+
+// setUmask reports that the umask is unavailable, as Windows has none.
+func setUmask(mask int) (func(), error) {
+	return nil, fmt.Errorf("cannot set umask %#o: %w", mask, errors.ErrUnsupported)
+}
@@ -0,0 +1,227 @@
+package proc
+
+import (
+	"bytes"
+	"cmp"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// streamMode enumerates the destinations of a Stream.
+type streamMode int
+
+const (
+	streamInherit streamMode = iota
+	streamPipe
+	streamDiscard
+	streamCustom
+)
+
+// Stream selects where an output stream of a command run by Exec goes.
+// Use one of Inherit, Pipe, Discard or Custom.
+type Stream struct {
+	mode streamMode
+	w    io.Writer
+}
+
+var (
+	// Inherit passes the stream through to the corresponding stream of the
+	// current process, or to ExecOptions.Stdout/Stderr when set. It is the
+	// zero value of Stream.
+	Inherit = Stream{}
+	// Pipe captures the stream into the ExecResult returned by Run.
+	Pipe = Stream{mode: streamPipe}
+	// Discard drops the stream.
+	Discard = Stream{mode: streamDiscard}
+)
+
+// Custom writes the stream to w.
+func Custom(w io.Writer) Stream {
+	return Stream{mode: streamCustom, w: w}
+}
+
+// writer returns the writer the stream resolves to. w is the writer from
+// ExecOptions honored by Inherit, std the stream of the current process
+// and buf the buffer used by Pipe. A nil result discards the stream.
+func (s Stream) writer(w io.Writer, std *os.File, buf *bytes.Buffer) io.Writer {
+	switch s.mode {
+	case streamPipe:
+		return buf
+	case streamDiscard:
+		return nil
+	case streamCustom:
+		return s.w
+	default:
+		return cmp.Or[io.Writer](w, std)
+	}
+}
+
+// prefixOutput makes the output streams of cmd selected by stdout and
+// stderr prepend prefix to every line. Discarded streams are left alone.
+func prefixOutput(cmd *exec.Cmd, prefix string, stdout, stderr bool) {
+	wrap := func(w io.Writer) io.Writer {
+		if w == nil {
+			return nil
+		}
+		return &prefixWriter{w: w, prefix: []byte(prefix)}
+	}
+	if stdout && stderr && sameWriter(cmd.Stdout, cmd.Stderr) {
+		// share the line state, as exec shares one pipe for both
+		cmd.Stdout = wrap(cmd.Stdout)
+		cmd.Stderr = cmd.Stdout
+		return
+	}
+	if stdout {
+		cmd.Stdout = wrap(cmd.Stdout)
+	}
+	if stderr {
+		cmd.Stderr = wrap(cmd.Stderr)
+	}
+}
+
+// prefixWriter prepends prefix to every line written to w. Lines may be
+// split across writes; the prefix is written when a line starts.
+type prefixWriter struct {
+	w       io.Writer
+	prefix  []byte
+	midLine bool
+	buf     []byte
+}
+
+// Write implements io.Writer.
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buf = pw.buf[:0]
+	for rest := p; len(rest) > 0; {
+		if !pw.midLine {
+			pw.buf = append(pw.buf, pw.prefix...)
+		}
+		line, after, found := bytes.Cut(rest, []byte{'\n'})
+		pw.buf = append(pw.buf, line...)
+		if found {
+			pw.buf = append(pw.buf, '\n')
+		}
+		pw.midLine = !found
+		rest = after
+	}
+	if _, err := pw.w.Write(pw.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// stderrTailSize is the number of trailing bytes of standard error kept for
+// the message of an ExitError.
+const stderrTailSize = 2048
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+// Write implements io.Writer.
+func (tb *tailBuffer) Write(p []byte) (int, error) {
+	tb.buf = append(tb.buf, p...)
+	if over := len(tb.buf) - tb.max; over > 0 {
+		tb.buf = append(tb.buf[:0], tb.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// tail returns the last max bytes of b, trimmed of surrounding whitespace
+// and of a UTF-8 sequence cut in half at the start.
+func tail(b []byte, max int) string {
+	if len(b) > max {
+		b = b[len(b)-max:]
+		for len(b) > 0 && !utf8.RuneStart(b[0]) {
+			b = b[1:]
+		}
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// limitOutput caps the combined output of cmd to max bytes, calling
+// exceeded once the cap is hit. Discarded streams are not counted.
+func limitOutput(cmd *exec.Cmd, max int64, exceeded func()) {
+	remaining := new(atomic.Int64)
+	remaining.Store(max)
+	limit := func(w io.Writer) io.Writer {
+		if w == nil {
+			return nil
+		}
+		return &limitWriter{w: w, remaining: remaining, exceeded: exceeded}
+	}
+	if sameWriter(cmd.Stdout, cmd.Stderr) {
+		// keep a single writer so exec still shares one pipe for both
+		cmd.Stdout = limit(cmd.Stdout)
+		cmd.Stderr = cmd.Stdout
+		return
+	}
+	cmd.Stdout = limit(cmd.Stdout)
+	cmd.Stderr = limit(cmd.Stderr)
+}
+
+// watchOutput calls seen on every write cmd makes to its output, including
+// discarded streams.
+func watchOutput(cmd *exec.Cmd, seen func()) {
+	watch := func(w io.Writer) io.Writer {
+		return &watchWriter{w: cmp.Or(w, io.Discard), seen: seen}
+	}
+	if sameWriter(cmd.Stdout, cmd.Stderr) {
+		cmd.Stdout = watch(cmd.Stdout)
+		cmd.Stderr = cmd.Stdout
+		return
+	}
+	cmd.Stdout = watch(cmd.Stdout)
+	cmd.Stderr = watch(cmd.Stderr)
+}
+
+// watchWriter calls seen before forwarding each write to w.
+type watchWriter struct {
+	w    io.Writer
+	seen func()
+}
+
+// Write implements io.Writer.
+func (ww *watchWriter) Write(p []byte) (int, error) {
+	ww.seen()
+	return ww.w.Write(p)
+}
+
+// limitWriter forwards writes to w as long as the shared remaining budget
+// allows. Once a write exceeds the budget, the part that still fits is
+// written, exceeded is called and ErrOutputTooLarge is returned.
+type limitWriter struct {
+	w         io.Writer
+	remaining *atomic.Int64
+	exceeded  func()
+}
+
+// Write implements io.Writer.
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	rem := lw.remaining.Add(-int64(len(p)))
+	if rem >= 0 {
+		return lw.w.Write(p)
+	}
+	lw.exceeded()
+	n := 0
+	if keep := int64(len(p)) + rem; keep > 0 {
+		n, _ = lw.w.Write(p[:keep])
+	}
+	return n, ErrOutputTooLarge
+}
+
+// sameWriter reports whether a and b are the same writer. Like os/exec, it
+// treats writers of non-comparable types as different rather than panicking.
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
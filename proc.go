@@ -20,20 +20,35 @@ var (
 	ctx context.Context
 )
 
-// init initializes the process information and registers signal listeners.
+// init initializes the process information and, unless PROC_NOSIGNAL is
+// set, starts signal handling.
 func init() {
-	var err error
-	workdir, err = os.Getwd()
-	if err != nil {
+	if err := Reinit(); err != nil {
 		panic(err)
 	}
 
-	name = filepath.Base(os.Args[0])
-	pid = os.Getpid()
 	Logger = os.Stdout
+	childKillDelay.Store(int64(DefaultChildKillDelay))
 	ctx = context.Background()
 
-	registerSignalListener()
+	if os.Getenv("PROC_NOSIGNAL") == "" {
+		StartSignalHandling()
+	}
+}
+
+// Reinit re-derives the process information returned by Pid, Name and
+// WorkDir, e.g. after os.Chdir or in tests simulating another process. It
+// is called on import, which panics if it fails. Reinit is not safe to call
+// concurrently with functions reading that information, such as Exec.
+func Reinit() error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get the working directory: %w", err)
+	}
+	workdir = wd
+	name = filepath.Base(os.Args[0])
+	pid = os.Getpid()
+	return nil
 }
 
 // Pid returns pid of the current process.
@@ -41,6 +56,17 @@ func Pid() int {
 	return pid
 }
 
+// IsInit reports whether the current process runs as PID 1, e.g. as the
+// entrypoint of a container. The kernel then ignores signals for which
+// no handler is installed, so a SIGTERM sent by an orchestrator (docker
+// stop, Kubernetes pod termination) only shuts the process down while
+// signal handling is running; otherwise it is ignored until the grace
+// period ends with SIGKILL. PID 1 also inherits orphaned processes and
+// must reap them, see EnableReaping.
+func IsInit() bool {
+	return pid == 1
+}
+
 // Name returns the process name, same as the command name.
 func Name() string {
 	return name
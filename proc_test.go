@@ -95,6 +95,23 @@ func TestContext_NotNil(t *testing.T) {
 	}
 }
 
+func TestReinit(t *testing.T) {
+	t.Cleanup(func() { _ = Reinit() })
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if err := Reinit(); err != nil {
+		t.Fatalf("Reinit: %v", err)
+	}
+	want, _ := filepath.EvalSymlinks(dir)
+	if got, _ := filepath.EvalSymlinks(WorkDir()); got != want {
+		t.Fatalf("WorkDir() = %q after Reinit, want %q", got, want)
+	}
+	if Pid() != os.Getpid() {
+		t.Fatalf("Pid() = %d, want %d", Pid(), os.Getpid())
+	}
+}
+
 func TestSignal_On_Once_Cancel_Notify(t *testing.T) {
 	// Use SIGTERM which is registered by default in registerSignalListener.
 	// We call Notify directly (not via OS) so no os.Exit occurs.
@@ -0,0 +1,26 @@
+//go:build linux
+// +build linux
+
+package proc
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// SignalThread sends sig to the thread tid of the current process with
+// tgkill, e.g. to interrupt a blocking system call in a thread pinned with
+// runtime.LockOSThread, whose ID syscall.Gettid returns. tgkill requires
+// the thread group ID, which is the PID of the current process, so a
+// thread of another process cannot be signalled and a stale tid that was
+// reused by another process is not hit by accident. Go threads are
+// otherwise interchangeable: a signal sent to a thread that is not locked
+// is handled like any other signal, and fatal signals kill the whole
+// process. Linux only; elsewhere it returns an error matching
+// errors.ErrUnsupported.
+func SignalThread(tid int, sig syscall.Signal) error {
+	if tid <= 0 {
+		return fmt.Errorf("cannot send %v to thread %d: invalid thread ID", sig, tid)
+	}
+	return syscall.Tgkill(pid, tid, sig)
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+package proc
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignalThread(t *testing.T) {
+	got := make(chan struct{}, 1)
+	defer Cancel(On(syscall.SIGUSR2, func() { got <- struct{}{} }))
+
+	errc := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		errc <- SignalThread(syscall.Gettid(), syscall.SIGUSR2)
+	}()
+	if err := <-errc; err != nil {
+		t.Fatalf("SignalThread failed: %v", err)
+	}
+	select {
+	case <-got:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the signal sent to the thread was not dispatched")
+	}
+
+	if err := SignalThread(0, syscall.SIGUSR2); err == nil {
+		t.Fatal("SignalThread(0) succeeded, want an error")
+	}
+	// a thread ID that is not a thread of this process
+	if err := SignalThread(1, syscall.Signal(0)); !errors.Is(err, syscall.ESRCH) {
+		t.Fatalf("SignalThread(1) = %v, want ESRCH", err)
+	}
+}
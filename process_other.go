@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// SignalThread reports that signalling a single thread is unavailable, as
+// it is only implemented on Linux.
+func SignalThread(tid int, sig syscall.Signal) error {
+	return fmt.Errorf("cannot send %v to thread %d: %w", sig, tid, errors.ErrUnsupported)
+}
@@ -0,0 +1,66 @@
+package proc
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsAlive_Self(t *testing.T) {
+	if !IsAlive(os.Getpid()) {
+		t.Fatal("current process should be alive")
+	}
+	if IsAlive(0) || IsAlive(-1) {
+		t.Fatal("non-positive PIDs should not be alive")
+	}
+}
+
+func TestSignalPID_Kill(t *testing.T) {
+	name, args := sleepCmd(5 * time.Second)
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start child: %v", err)
+	}
+	pid := cmd.Process.Pid
+	if !IsAlive(pid) {
+		t.Fatal("started child should be alive")
+	}
+
+	if err := SignalPID(pid, syscall.SIGKILL); err != nil {
+		t.Fatalf("SignalPID failed: %v", err)
+	}
+	_ = cmd.Wait()
+	if IsAlive(pid) {
+		t.Fatal("killed and reaped child should not be alive")
+	}
+}
+
+func TestSignalPID_InvalidPID(t *testing.T) {
+	// the null signal keeps a regression harmless: kill(-1, 0) signals nothing
+	for _, pid := range []int{0, -1} {
+		if err := SignalPID(pid, 0); err == nil {
+			t.Fatalf("SignalPID(%d) succeeded, want an error", pid)
+		}
+	}
+}
+
+func TestSignalSelf(t *testing.T) {
+	got := make(chan struct{}, 1)
+	id := Once(syscall.SIGALRM, func() { got <- struct{}{} })
+	defer Cancel(id)
+
+	if err := SignalSelf(syscall.SIGALRM); err != nil {
+		t.Fatalf("SignalSelf failed: %v", err)
+	}
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("listener was not called")
+	}
+
+	if err := SignalSelf(bogusSignal{}); err == nil {
+		t.Fatal("SignalSelf should reject signals that are not syscall.Signal")
+	}
+}
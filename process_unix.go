@@ -0,0 +1,46 @@
+//go:build !windows
+// +build !windows
+
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// SignalPID sends sig to the process with the given PID, e.g. one read from
+// a pidfile written by another tool. On Windows only SIGKILL is supported.
+// A PID <= 0 returns an error instead of signalling a process group or
+// every process, as kill(2) would.
+func SignalPID(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return fmt.Errorf("cannot send %v to process %d: invalid PID", sig, pid)
+	}
+	return syscall.Kill(pid, sig)
+}
+
+// IsAlive reports whether a process with the given PID exists, by sending
+// it the null signal. A process owned by another user counts as alive.
+// Note that a zombie, i.e. an exited process not yet waited for by its
+// parent, still counts as alive.
+func IsAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// SignalSelf sends sig to the current process, which then handles it like
+// any signal received from the OS: shutdown signals shut the process down,
+// others are dispatched to their listeners. It is the portable way to
+// trigger the process's own handlers; on Windows signals are simulated.
+func SignalSelf(sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("cannot send %v: not a syscall.Signal", sig)
+	}
+	return syscall.Kill(pid, s)
+}
@@ -0,0 +1,78 @@
+//go:build windows
+// +build windows
+
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// processQueryLimitedInformation is the PROCESS_QUERY_LIMITED_INFORMATION
+// access right, which is granted for more processes than
+// PROCESS_QUERY_INFORMATION.
+const processQueryLimitedInformation = 0x1000
+
+// stillActive is the exit code GetExitCodeProcess reports for a process
+// that is still running (STILL_ACTIVE).
+const stillActive = 259
+
+// SignalPID sends sig to the process with the given PID, e.g. one read from
+// a pidfile written by another tool.
+//
+// Windows has no Unix-style signals: only SIGKILL is supported, which
+// terminates the process via TerminateProcess. Any other signal returns an
+// error matching errors.ErrUnsupported. A PID <= 0 returns an error.
+func SignalPID(pid int, sig syscall.Signal) error {
+	if pid <= 0 {
+		return fmt.Errorf("cannot send %v to process %d: invalid PID", sig, pid)
+	}
+	if sig != syscall.SIGKILL {
+		return fmt.Errorf("cannot send %v to process %d: %w", sig, pid, errors.ErrUnsupported)
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// IsAlive reports whether a process with the given PID is running. On
+// Windows it opens the process and checks that it has not exited yet; a
+// process that cannot be opened, e.g. because of missing access rights,
+// is reported as not alive.
+func IsAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// SignalSelf sends sig to the current process, which then handles it like
+// any signal received from the OS: shutdown signals shut the process down,
+// others are dispatched to their listeners. It is the portable way to
+// trigger the process's own handlers.
+//
+// Windows cannot deliver signals to a process, so SignalSelf feeds sig to
+// the package's signal handling directly, as SendAfter does; handlers
+// installed with signal.Notify outside of this package do not see it. Of
+// the signals Windows itself raises, only os.Interrupt (Ctrl+C) and
+// SIGTERM (console close, logoff and shutdown) ever arrive from the OS.
+func SignalSelf(sig os.Signal) error {
+	if signum(sig) == -1 {
+		return fmt.Errorf("cannot send %v: not a valid signal", sig)
+	}
+	go handle(sig)
+	return nil
+}
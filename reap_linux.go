@@ -0,0 +1,126 @@
+//go:build linux
+// +build linux
+
+package proc
+
+import (
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	// reapOnce ensures the reaper is only installed once.
+	reapOnce sync.Once
+	// reaping reports whether EnableReaping was called.
+	reaping atomic.Bool
+	// reapMu is held for writing by the reaper, and for reading while
+	// starting a command that waits for its child itself, so the reaper
+	// cannot take a child before it is registered in waited.
+	reapMu sync.RWMutex
+	// waitedMu protects waited.
+	waitedMu sync.Mutex
+	// waited holds the PIDs of the children started by Exec, which the
+	// reaper leaves to their own Wait.
+	waited = map[int]bool{}
+)
+
+// EnableReaping makes the process reap its exited children on SIGCHLD, as
+// an init process must do for the orphans it inherits; otherwise they stay
+// zombies. It is typically used as
+//
+//	if proc.IsInit() {
+//		proc.EnableReaping()
+//	}
+//
+// The commands run by Exec, Run and Start are left to their own Wait. Any
+// other child is reaped, so the reaper competes with exec.Cmd.Wait for
+// commands started otherwise, e.g. with os/exec directly: if it wins, their
+// Wait fails with ECHILD and the exit status is lost. Enable it only if
+// that is acceptable. Calling it more than once has no further effect. It
+// is a no-op on platforms other than Linux.
+func EnableReaping() {
+	reapOnce.Do(func() {
+		reaping.Store(true)
+		On(syscall.SIGCHLD, reap)
+		// collect children that exited before the listener was installed
+		go reap()
+	})
+}
+
+// startWaited starts cmd, whose child the caller waits for itself, keeping
+// the reaper away from it until release is called once it has been waited
+// for.
+func startWaited(cmd *exec.Cmd) (release func(), err error) {
+	reapMu.RLock()
+	err = cmd.Start()
+	if err == nil {
+		waitedMu.Lock()
+		waited[cmd.Process.Pid] = true
+		waitedMu.Unlock()
+	}
+	reapMu.RUnlock()
+	if err != nil {
+		return func() {}, err
+	}
+	pid := cmd.Process.Pid
+	return func() {
+		waitedMu.Lock()
+		delete(waited, pid)
+		waitedMu.Unlock()
+		if reaping.Load() {
+			// the reaper stops at a waited child that has exited, so the
+			// children that exited after it are collected now
+			go reap()
+		}
+	}, nil
+}
+
+// reap waits for exited children without blocking until there are none,
+// or the next one is a child started by Exec.
+func reap() {
+	reapMu.Lock()
+	defer reapMu.Unlock()
+	for {
+		child, err := nextExited()
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || child <= 0 {
+			return
+		}
+		waitedMu.Lock()
+		skip := waited[child]
+		waitedMu.Unlock()
+		if skip {
+			return
+		}
+		var ws syscall.WaitStatus
+		if _, err := syscall.Wait4(child, &ws, syscall.WNOHANG, nil); err != nil {
+			return
+		}
+		logEvent("child_reaped", fields{"child_pid": child, "status": ws.ExitStatus()}, "PID %d. Reaped child %d, %v.", pid, child, ws)
+	}
+}
+
+// nextExited returns the PID of an exited child without reaping it, or 0
+// if there is none, using waitid with WNOWAIT, which the syscall package
+// does not wrap.
+func nextExited() (int, error) {
+	const pAll = 0
+	// siginfo_t is 128 bytes; si_pid follows si_signo, si_errno and
+	// si_code, aligned to the size of a pointer
+	var info [16]uint64
+	_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, pAll, 0, uintptr(unsafe.Pointer(&info[0])),
+		syscall.WEXITED|syscall.WNOHANG|syscall.WNOWAIT, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	offset := 12
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		offset = 16
+	}
+	return int(*(*int32)(unsafe.Add(unsafe.Pointer(&info[0]), offset))), nil
+}
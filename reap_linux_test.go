@@ -0,0 +1,88 @@
+//go:build linux
+
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reapEnv makes TestEnableReaping_Helper run when the test binary is run as
+// a helper process; reaping is not enabled in the test binary itself since
+// it would interfere with the Wait of other tests' commands.
+const reapEnv = "PROC_TEST_REAP"
+
+func TestEnableReaping(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEnableReaping_Helper$")
+	cmd.Env = append(os.Environ(), reapEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("helper failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "reaped") {
+		t.Fatalf("helper output should report the child reaped, got:\n%s", out)
+	}
+}
+
+func TestEnableReaping_Helper(t *testing.T) {
+	if os.Getenv(reapEnv) == "" {
+		t.Skip("only runs as a helper process")
+	}
+	EnableReaping()
+	EnableReaping()
+
+	// start a child that is never waited for
+	p, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "exit 0"}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatalf("failed to start child: %v", err)
+	}
+	dir := "/proc/" + strconv.Itoa(p.Pid)
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			fmt.Println("reaped")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("child %d was not reaped", p.Pid)
+}
+
+func TestEnableReaping_Exec(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestEnableReaping_ExecHelper$")
+	cmd.Env = append(os.Environ(), reapEnv+"=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("helper failed: %v\n%s", err, out)
+	}
+}
+
+func TestEnableReaping_ExecHelper(t *testing.T) {
+	if os.Getenv(reapEnv) == "" {
+		t.Skip("only runs as a helper process")
+	}
+	EnableReaping()
+
+	// orphans exiting alongside keep the reaper busy
+	for range 20 {
+		if _, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "exit 0"}, &os.ProcAttr{}); err != nil {
+			t.Fatalf("failed to start child: %v", err)
+		}
+		err := Exec(context.Background(), ExecOptions{Command: "sh", Args: []string{"-c", "exit 3"}})
+		var ee *ExitError
+		if !errors.As(err, &ee) || ee.Code != 3 {
+			t.Fatalf("Exec with reaping enabled = %v, want exit code 3", err)
+		}
+	}
+}
+
+func TestIsInit(t *testing.T) {
+	if IsInit() != (os.Getpid() == 1) {
+		t.Fatalf("IsInit() = %v for PID %d", IsInit(), os.Getpid())
+	}
+}
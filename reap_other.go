@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package proc
+
+import "os/exec"
+
+// EnableReaping makes the process reap its exited children, as an init
+// process must do. It is only implemented on Linux and a no-op elsewhere.
+func EnableReaping() {}
+
+// startWaited starts cmd; there is no reaper to keep away from it.
+func startWaited(cmd *exec.Cmd) (release func(), err error) {
+	return func() {}, cmd.Start()
+}
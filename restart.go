@@ -0,0 +1,33 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Restart replaces the running program with a fresh instance of the same
+// binary, started with the same arguments and environment. Listeners
+// registered for SIGTERM are notified first, so shutdown hooks run before
+// the current instance goes away.
+//
+// On Unix the process image is replaced in place via execve: the PID, the
+// parent process and open stdio are kept. Windows has no equivalent, so a
+// new process is spawned with the same stdio and the current one exits; the
+// restarted instance therefore gets a new PID there.
+//
+// Restart only returns if the restart failed.
+func Restart() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the executable: %w", err)
+	}
+
+	logEvent("restarting", fields{"executable": exe}, "PID %d. Restarting %s...", pid, exe)
+	Notify(syscall.SIGTERM)
+
+	if err := reexec(exe); err != nil {
+		return fmt.Errorf("failed to restart: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package proc
+
+import (
+	"os"
+	"syscall"
+)
+
+// reexec replaces the current process image with exe, keeping the PID.
+func reexec(exe string) error {
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// restartEnv drives TestRestart_Helper when the test binary is run as a
+// helper process: "1" restarts, "2" reports the restarted instance.
+const restartEnv = "PROC_TEST_RESTART"
+
+func TestRestart_KeepsPID(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRestart_Helper$")
+	cmd.Env = append(os.Environ(), restartEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("helper failed: %v\n%s", err, out)
+	}
+
+	want := "restarted pid=" + strconv.Itoa(cmd.Process.Pid)
+	if !strings.Contains(string(out), want) {
+		t.Fatalf("helper output should contain %q, got:\n%s", want, out)
+	}
+}
+
+func TestRestart_Helper(t *testing.T) {
+	switch os.Getenv(restartEnv) {
+	case "1":
+		os.Setenv(restartEnv, "2")
+		err := Restart()
+		t.Fatalf("Restart returned: %v", err)
+	case "2":
+		fmt.Printf("restarted pid=%d\n", os.Getpid())
+	default:
+		t.Skip("only runs as a helper process")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package proc
+
+import (
+	"os"
+	"os/exec"
+)
+
+// reexec starts exe as a new process sharing the current stdio, then exits
+// the current process.
+func reexec(exe string) error {
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = os.Environ()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exitFn(0)
+	return nil
+}
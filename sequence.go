@@ -0,0 +1,82 @@
+package proc
+
+import (
+	"context"
+	"fmt"
+)
+
+// Condition selects when a Step of a Sequence runs, relative to the outcome
+// of the last step that ran.
+type Condition int
+
+const (
+	// Success runs the step only if the last step that ran succeeded, like
+	// the shell's &&. A first step with this condition always runs. This is
+	// the default.
+	Success Condition = iota
+	// Failure runs the step only if the last step that ran failed, like
+	// the shell's ||. A first step with this condition never runs.
+	Failure
+	// Always runs the step regardless of the outcome, like the shell's ;.
+	Always
+)
+
+// Step is a command of a Sequence.
+type Step struct {
+	// Options configures the command, as for Run.
+	Options ExecOptions
+	// OnlyIf selects when the step runs, Success by default.
+	OnlyIf Condition
+}
+
+// SequenceResult describes the steps run by Sequence.
+type SequenceResult struct {
+	// Results holds the ExecResult of each step, in order. It is nil for
+	// steps that were skipped or could not be started.
+	Results []*ExecResult
+	// Ran reports, for each step, whether it was run.
+	Ran []bool
+	// Failed is the index of the step whose error Sequence returned, or -1
+	// if it returned nil.
+	Failed int
+}
+
+// Sequence runs steps one after another, each only if its condition holds
+// for the outcome of the last step that ran, so a skipped step does not
+// change the outcome, as in the shell: "build && test || report" runs
+// report if either build or test fails. This chains commands without a
+// shell, e.g. building, then testing, then deploying.
+//
+// Like the exit status of a shell list, the returned error is the error of
+// the last step that ran, annotated with its index, or nil if it succeeded.
+// The result is never nil.
+func Sequence(ctx context.Context, steps []Step) (*SequenceResult, error) {
+	res := &SequenceResult{
+		Results: make([]*ExecResult, len(steps)),
+		Ran:     make([]bool, len(steps)),
+		Failed:  -1,
+	}
+	var last error
+	for i, step := range steps {
+		switch step.OnlyIf {
+		case Success:
+			if last != nil {
+				continue
+			}
+		case Failure:
+			if last == nil {
+				continue
+			}
+		}
+
+		res.Ran[i] = true
+		res.Results[i], last = Run(ctx, step.Options)
+		if last != nil {
+			last = fmt.Errorf("step %d: %w", i, last)
+			res.Failed = i
+		} else {
+			res.Failed = -1
+		}
+	}
+	return res, last
+}
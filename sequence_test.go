@@ -0,0 +1,72 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func okStep(cond Condition) Step {
+	cmd, args := trivialEcho()
+	return Step{Options: ExecOptions{Command: cmd, Args: args, StdoutMode: Discard}, OnlyIf: cond}
+}
+
+func failStep(cond Condition) Step {
+	cmd, args := "sh", []string{"-c", "exit 1"}
+	if isWindows() {
+		cmd, args = "cmd", []string{"/C", "exit", "1"}
+	}
+	return Step{Options: ExecOptions{Command: cmd, Args: args, StderrMode: Discard}, OnlyIf: cond}
+}
+
+func TestSequence(t *testing.T) {
+	cases := []struct {
+		name    string
+		steps   []Step
+		wantRan []bool
+		failed  int
+	}{
+		{"success runs after success", []Step{okStep(Success), okStep(Success)}, []bool{true, true}, -1},
+		{"success skipped after failure", []Step{failStep(Success), okStep(Success)}, []bool{true, false}, 0},
+		{"failure runs after failure", []Step{failStep(Success), okStep(Failure)}, []bool{true, true}, -1},
+		{"failure skipped after success", []Step{okStep(Success), failStep(Failure)}, []bool{true, false}, -1},
+		{"failure first never runs", []Step{okStep(Failure)}, []bool{false}, -1},
+		{"always runs after failure", []Step{failStep(Success), failStep(Always)}, []bool{true, true}, 1},
+		{"always runs after success", []Step{okStep(Success), okStep(Always)}, []bool{true, true}, -1},
+		{
+			// build && test || report: a skipped step keeps the outcome
+			"skipped step keeps outcome",
+			[]Step{failStep(Success), okStep(Success), okStep(Failure)},
+			[]bool{true, false, true},
+			-1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Sequence(context.Background(), tc.steps)
+			if !slices.Equal(res.Ran, tc.wantRan) {
+				t.Fatalf("Ran = %v, want %v", res.Ran, tc.wantRan)
+			}
+			if res.Failed != tc.failed {
+				t.Fatalf("Failed = %d, want %d", res.Failed, tc.failed)
+			}
+			if (err != nil) != (tc.failed >= 0) {
+				t.Fatalf("err = %v, want an error only if a step failed", err)
+			}
+			for i, ran := range res.Ran {
+				if ran != (res.Results[i] != nil) {
+					t.Fatalf("step %d: ran %v but result %v", i, ran, res.Results[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSequence_ErrorNamesStep(t *testing.T) {
+	_, err := Sequence(context.Background(), []Step{okStep(Success), failStep(Success)})
+	if !errors.Is(err, ErrExitNonZero) || !strings.Contains(err.Error(), "step 1") {
+		t.Fatalf("err = %v, want ErrExitNonZero naming step 1", err)
+	}
+}
@@ -1,47 +1,713 @@
 package proc
 
 import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 // delayTimeBeforeForceQuit specifies the duration to wait before forcefully
-// killing the process. A value of 5500 milliseconds is typically used because
-// most queues operate in blocking mode with a 5-second timeout.
-var delayTimeBeforeForceQuit time.Duration
+// killing the process, in nanoseconds. It is 0 unless set, see
+// SetTimeToForceQuit. It is accessed atomically as it may be updated while
+// a signal-triggered shutdown is running.
+var delayTimeBeforeForceQuit atomic.Int64
+
+// forceQuitExtension is the sum of the active ExtendForceQuit extensions,
+// in nanoseconds.
+var forceQuitExtension atomic.Int64
+
+// DefaultTimeToForceQuit is the force-quit delay set by
+// SetDefaultTimeToForceQuit. It is slightly longer than the 5-second
+// timeout most queues block with, so in-flight consumers get to finish.
+const DefaultTimeToForceQuit = 5500 * time.Millisecond
 
 // killFn is the function used to kill the process. It can be stubbed in tests
-// to verify shutdown behavior without actually killing the process.
+// to verify shutdown behavior without actually killing the process, and is
+// replaced by SetKillFunc.
 var killFn = kill
 
-// SetTimeToForceQuit sets the duration to wait before forcefully killing
-// the process during shutdown. If set to 0, the process will be killed
-// immediately without attempting graceful shutdown.
+// DefaultChildKillDelay is the pause between killing the commands still
+// running in Exec and killing the process during a force quit, unless
+// changed with SetChildKillDelay.
+const DefaultChildKillDelay = 100 * time.Millisecond
+
+// childKillDelay is the delay set by SetChildKillDelay, in nanoseconds.
+// It is set to DefaultChildKillDelay on import.
+var childKillDelay atomic.Int64
+
+// settleDelay is the delay set by SetSettleDelay, in nanoseconds.
+var settleDelay atomic.Int64
+
+// killSignal is the signal set by SetKillSignal, 0 meaning SIGKILL.
+var killSignal atomic.Int32
+
+// shutdownObserver is the function set by SetShutdownObserver, nil if none.
+var shutdownObserver atomic.Pointer[func(ShutdownStats)]
+
+// forceKillHook is the function set by OnForceKill, nil if none.
+var forceKillHook atomic.Pointer[func()]
+
+// exitFn is the function used to exit the process. It can be stubbed in tests
+// to verify exit paths without terminating the test binary.
+var exitFn = os.Exit
+
+var (
+	// shuttingDown reports whether a shutdown has begun.
+	shuttingDown atomic.Bool
+	// shutdownDone is closed when a shutdown begins, see Done.
+	shutdownDone = make(chan struct{})
+	// abortMu protects abortShutdown.
+	abortMu sync.Mutex
+	// abortShutdown is closed by CancelShutdown to abort the Shutdown
+	// waiting out its force-quit delay, nil if none is.
+	abortShutdown chan struct{}
+)
+
+var (
+	// watchdog is the hard cap on the duration of a shutdown, see SetWatchdog.
+	watchdog atomic.Int64
+	// watchdogMu protects watchdogTimer.
+	watchdogMu sync.Mutex
+	// watchdogTimer is the armed watchdog, nil until a shutdown starts.
+	watchdogTimer *time.Timer
+)
+
+// Done returns a channel that is closed when the process begins shutting
+// down, i.e. when Shutdown is first called, before any listener is
+// notified. Goroutines can select on it to exit cleanly, e.g. a pool of
+// workers, without registering a listener each. It is closed only once,
+// however many shutdowns are triggered.
+func Done() <-chan struct{} {
+	return shutdownDone
+}
+
+// IsShuttingDown reports whether the process has begun shutting down, e.g.
+// so an HTTP handler can reject new requests with 503 while in-flight ones
+// drain. It turns true when Shutdown is first called, before any listener
+// is notified, together with the closing of the channel returned by Done,
+// and stays true even if the shutdown is cancelled.
+func IsShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+// beginShutdown marks the process as shutting down and closes the channel
+// returned by Done, once.
+func beginShutdown() {
+	if shuttingDown.CompareAndSwap(false, true) {
+		close(shutdownDone)
+	}
+}
+
+// SetTimeToForceQuit sets the duration Shutdown gives the SIGTERM listeners
+// before forcefully killing the process. If they return earlier, the
+// process is killed right away with the received signal. If set to 0, which
+// is the default, Shutdown waits for the listeners to return, however long
+// that takes, and kills the process right after.
 func SetTimeToForceQuit(duration time.Duration) {
-	delayTimeBeforeForceQuit = duration
+	delayTimeBeforeForceQuit.Store(int64(duration))
+}
+
+// SetDefaultTimeToForceQuit sets the force-quit delay to
+// DefaultTimeToForceQuit, a sensible value for processes that consume
+// queues. The delay is 0 unless set.
+func SetDefaultTimeToForceQuit() {
+	SetTimeToForceQuit(DefaultTimeToForceQuit)
+}
+
+// SetTimeToForceQuitString is like SetTimeToForceQuit for a duration in
+// the format of time.ParseDuration, e.g. "5s500ms" from an environment
+// variable or a flag. It returns an error, leaving the delay unchanged, if
+// s is not a valid duration or is negative. The String method of the
+// duration returned by TimeToForceQuit yields a value it accepts.
+func SetTimeToForceQuitString(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid force-quit delay: %w", err)
+	}
+	if d < 0 {
+		return fmt.Errorf("force-quit delay must not be negative, got %v", d)
+	}
+	SetTimeToForceQuit(d)
+	return nil
+}
+
+// TimeToForceQuit returns the duration Shutdown waits before forcefully
+// killing the process, as set by SetTimeToForceQuit plus the active
+// extensions of ExtendForceQuit.
+func TimeToForceQuit() time.Duration {
+	d := time.Duration(delayTimeBeforeForceQuit.Load())
+	if d == 0 {
+		return 0
+	}
+	return d + time.Duration(forceQuitExtension.Load())
+}
+
+// ExtendForceQuit lengthens the force-quit delay by d until the returned
+// restore function is called, e.g. so a SIGTERM during a long migration
+// does not kill the process before the migration can wind down.
+// Extensions stack and may be restored in any order, and the delay set by
+// SetTimeToForceQuit meanwhile still applies underneath. A delay of 0 is
+// not extended, as Shutdown then waits for the listeners without limit
+// anyway. Calling restore more than once has no further effect.
+//
+// The extension applies to shutdowns starting while it is active: a
+// shutdown already waiting out its delay is not affected.
+func ExtendForceQuit(d time.Duration) (restore func()) {
+	d = max(d, 0)
+	forceQuitExtension.Add(int64(d))
+	var once sync.Once
+	return func() {
+		once.Do(func() { forceQuitExtension.Add(-int64(d)) })
+	}
+}
+
+// SetKillSignal sets the signal Shutdown kills the process with once the
+// force-quit delay has elapsed, SIGKILL by default. Passing 0 restores the
+// default. On Windows the signal is ignored, as the process is always
+// terminated.
+func SetKillSignal(sig syscall.Signal) {
+	killSignal.Store(int32(sig))
+}
+
+// forceKillSignal returns the signal set by SetKillSignal.
+func forceKillSignal() syscall.Signal {
+	if sig := syscall.Signal(killSignal.Load()); sig != 0 {
+		return sig
+	}
+	return syscall.SIGKILL
+}
+
+// SetKillFunc replaces the function Shutdown uses to terminate the process
+// once listeners have been notified. The function receives the signal
+// passed to Shutdown, and its error is returned by Shutdown. This allows
+// custom strategies, e.g. running taskkill on Windows or signalling a
+// container's PID namespace. Passing nil restores the default platform
+// implementation, which sends the signal to the current process on Unix and
+// kills the process on Windows. A shutdown triggered by a signal or by
+// InitiateShutdown exits the process on its own, so it only calls fn to
+// force-kill the process once the force-quit delay has elapsed.
+//
+// SetKillFunc should be called during initialization, before a shutdown
+// can start.
+func SetKillFunc(fn func(sig syscall.Signal) error) {
+	if fn == nil {
+		fn = kill
+	}
+	killFn = fn
+}
+
+// SetChildKillDelay sets how long a force quit waits after killing the
+// process groups of the commands still running in Exec before it kills the
+// process itself, so their exit is observed and logged rather than leaving
+// orphans behind. The pause only happens if such commands exist, and adds
+// to the force-quit delay. If set to 0, the children are killed right
+// before the process. Defaults to DefaultChildKillDelay.
+func SetChildKillDelay(d time.Duration) {
+	childKillDelay.Store(int64(max(d, 0)))
+}
+
+// SetSettleDelay sets how long a shutdown without force-quit delay waits
+// after the SIGTERM listeners returned before it kills the process. The
+// listeners themselves always run to completion before the kill, as
+// Notify waits for them; the delay leaves time for work they hand off to
+// goroutines of their own, e.g. flushing a buffered logger. Defaults to 0,
+// killing the process right away.
+func SetSettleDelay(d time.Duration) {
+	settleDelay.Store(int64(max(d, 0)))
+}
+
+// SetWatchdog sets a hard cap on how long a shutdown may take. When set to a
+// positive duration, a timer is armed as soon as Shutdown starts; if the
+// process is still alive when it fires, the stacks of all goroutines are
+// logged and the process exits with status 1. This guards against listeners
+// that deadlock and block the regular force-quit logic. A shutdown aborted
+// by CancelShutdown disarms it. If set to 0, the default, no watchdog is
+// armed.
+func SetWatchdog(duration time.Duration) {
+	watchdog.Store(int64(duration))
+}
+
+// armWatchdog starts the watchdog timer if one is configured and not
+// already running.
+func armWatchdog() {
+	d := time.Duration(watchdog.Load())
+	if d <= 0 {
+		return
+	}
+
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+
+	if watchdogTimer != nil {
+		return
+	}
+	watchdogTimer = time.AfterFunc(d, func() {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		logEvent("watchdog_expired", fields{"after": d, "stacks": string(buf)},
+			"Still alive %v after shutdown started, watchdog exiting the process...\n%s", d, buf)
+		exitFn(1)
+	})
+}
+
+// disarmWatchdog stops the watchdog timer, if armed, so the next shutdown
+// arms a fresh one.
+func disarmWatchdog() {
+	watchdogMu.Lock()
+	defer watchdogMu.Unlock()
+	if watchdogTimer != nil {
+		watchdogTimer.Stop()
+		watchdogTimer = nil
+	}
+}
+
+// ShutdownOrder selects when the commands run by Exec are stopped during a
+// shutdown relative to the SIGTERM listeners registered by the application.
+type ShutdownOrder int
+
+const (
+	// ChildrenFirst stops running Exec commands and waits for them to exit
+	// before notifying the listeners, e.g. so a database a child uses is
+	// closed only once the child is gone. This is the default.
+	ChildrenFirst ShutdownOrder = iota
+	// ListenersFirst notifies the listeners and waits for them to return
+	// before stopping running Exec commands.
+	ListenersFirst
+)
+
+var (
+	// shutdownOrder is the ShutdownOrder set by SetShutdownOrder.
+	shutdownOrder atomic.Int32
+	// hooksMu protects shutdownHooks.
+	hooksMu sync.Mutex
+	// shutdownHooks holds the functions registered with onShutdown.
+	shutdownHooks = map[uint32]func(){}
+)
+
+// exitHook is a function registered with OnShutdownWithCode.
+type exitHook struct {
+	id uint32
+	fn func(code int) int
+}
+
+var (
+	// exitHooksMu protects exitHooks.
+	exitHooksMu sync.Mutex
+	// exitHooks holds the functions registered with OnShutdownWithCode, in
+	// registration order.
+	exitHooks []exitHook
+)
+
+// OnShutdownWithCode registers fn to observe and adjust the exit code of
+// the process once a shutdown triggered by a signal or by InitiateShutdown
+// has completed, right before it exits. The hooks run one after another in
+// registration order, each receiving the code returned by the previous one,
+// starting at 0, so the last hook has the final word. A hook that panics
+// leaves the code unchanged.
+//
+// The hooks do not run if the process is killed with the force-quit signal,
+// i.e. when the listeners outlive the force-quit delay, or the shutdown is
+// cancelled, nor after Shutdown and ShutdownNow, which
+// never exit. The returned function removes fn.
+func OnShutdownWithCode(fn func(code int) int) (remove func()) {
+	id := atomic.AddUint32(&seq, 1)
+	exitHooksMu.Lock()
+	exitHooks = append(exitHooks, exitHook{id: id, fn: fn})
+	exitHooksMu.Unlock()
+	return func() {
+		exitHooksMu.Lock()
+		exitHooks = slices.DeleteFunc(exitHooks, func(h exitHook) bool { return h.id == id })
+		exitHooksMu.Unlock()
+	}
+}
+
+// exitCode returns the code the process exits with after a shutdown, as
+// adjusted by the hooks registered with OnShutdownWithCode.
+func exitCode() int {
+	exitHooksMu.Lock()
+	hooks := slices.Clone(exitHooks)
+	exitHooksMu.Unlock()
+
+	code := 0
+	for _, h := range hooks {
+		func() {
+			defer recovery()
+			code = h.fn(code)
+		}()
+	}
+	return code
+}
+
+// SetShutdownOrder sets when the commands run by Exec are stopped during a
+// shutdown relative to the SIGTERM listeners, ChildrenFirst by default.
+func SetShutdownOrder(order ShutdownOrder) {
+	shutdownOrder.Store(int32(order))
+}
+
+// onShutdown registers fn to run once when a shutdown begins. The returned
+// function removes fn if it has not run yet.
+func onShutdown(fn func()) (remove func()) {
+	id := atomic.AddUint32(&seq, 1)
+	hooksMu.Lock()
+	shutdownHooks[id] = fn
+	hooksMu.Unlock()
+	return func() {
+		hooksMu.Lock()
+		delete(shutdownHooks, id)
+		hooksMu.Unlock()
+	}
+}
+
+// runShutdownHooks runs and removes the functions registered with
+// onShutdown concurrently, and waits for them to return.
+func runShutdownHooks() {
+	hooksMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = map[uint32]func(){}
+	hooksMu.Unlock()
+
+	var wg sync.WaitGroup
+	run := safeRunner(&wg)
+	for _, fn := range hooks {
+		run(fn)
+	}
+	wg.Wait()
+}
+
+// notifyShutdown stops the commands run by Exec and notifies the listeners
+// of sig, in the order set by SetShutdownOrder. The SIGTERM listeners are
+// notified whatever the signal, since the package's own shutdown hooks
+// register there. It returns how long each of the two phases took.
+func notifyShutdown(sig syscall.Signal) (children, listeners time.Duration) {
+	stopChildren := func() {
+		start := time.Now()
+		runShutdownHooks()
+		children = time.Since(start)
+	}
+	notify := func() {
+		start := time.Now()
+		if sig != syscall.SIGTERM {
+			Notify(sig)
+		}
+		Notify(syscall.SIGTERM)
+		listeners = time.Since(start)
+	}
+	if ShutdownOrder(shutdownOrder.Load()) == ListenersFirst {
+		notify()
+		stopChildren()
+	} else {
+		stopChildren()
+		notify()
+	}
+	return children, listeners
+}
+
+// ShutdownStats reports how long the phases of a shutdown took, e.g. to
+// tune SetTimeToForceQuit: a 5.5s delay is oversized if the listeners
+// finish in 200ms.
+type ShutdownStats struct {
+	// Children is the time spent stopping the commands run by Exec.
+	Children time.Duration
+	// Listeners is the time until all listeners notified of the shutdown
+	// returned.
+	Listeners time.Duration
+	// Total is the time from the start of Shutdown until the process was
+	// about to be killed: the listeners returned or the force-quit delay
+	// elapsed, whichever came first.
+	Total time.Duration
+	// Finished reports whether both phases completed before the kill. If
+	// false, the force-quit delay elapsed first and Children and Listeners
+	// are zero.
+	Finished bool
+}
+
+// SetShutdownObserver installs fn to receive the ShutdownStats of every
+// shutdown, right before the process is killed. A panic in fn is logged and
+// does not prevent the kill. Passing nil removes it.
+func SetShutdownObserver(fn func(ShutdownStats)) {
+	if fn == nil {
+		shutdownObserver.Store(nil)
+		return
+	}
+	shutdownObserver.Store(&fn)
+}
+
+// OnForceKill installs fn to run when a shutdown force kills the process
+// once the force-quit delay has elapsed, e.g. to count forced shutdowns in
+// a metric. It runs synchronously right before the kill, and not at all
+// when the listeners return within the delay or there is no delay, where
+// the process is killed as soon as they returned. As the kill terminates the
+// process, fn must be quick: anything it hands off to another goroutine or
+// leaves buffered is lost. A panic in fn is logged and does not prevent
+// the kill. It replaces any previous function; passing nil removes it.
+func OnForceKill(fn func()) {
+	if fn == nil {
+		forceKillHook.Store(nil)
+		return
+	}
+	forceKillHook.Store(&fn)
+}
+
+// OnShutdownTimeout registers fn to run once when the process shuts down,
+// with its own deadline: the context passed to fn is cancelled after d, and
+// if fn has not returned by then it is logged and abandoned, so one slow
+// hook does not eat the whole shutdown budget of the others. An abandoned
+// fn keeps running in the background until it returns or the process
+// exits. Errors returned by fn, and panics, are written to Logger.
+//
+// Returns a unique ID that can be used with Cancel to remove the hook.
+func OnShutdownTimeout(d time.Duration, fn func(ctx context.Context) error) uint32 {
+	return Once(syscall.SIGTERM, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					done <- fmt.Errorf("panic: %v", p)
+				}
+			}()
+			done <- fn(ctx)
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				logEvent("shutdown_hook_failed", fields{"error": err}, "PID %d. Shutdown hook failed: %v.", pid, err)
+			}
+		case <-ctx.Done():
+			logEvent("shutdown_hook_abandoned", fields{"timeout": d}, "PID %d. Shutdown hook did not return within %v, abandoning it.", pid, d)
+		}
+	})
+}
+
+// CloseOnShutdown registers c to be closed once when the process shuts
+// down, e.g. a net.Listener so no new connections are accepted while the
+// listeners drain the open ones. It runs with the other SIGTERM listeners,
+// within the force-quit delay. An error returned by Close is written to
+// Logger.
+//
+// Returns a unique ID that can be used with Cancel to remove the hook.
+func CloseOnShutdown(c io.Closer) uint32 {
+	return Once(syscall.SIGTERM, func() {
+		if err := c.Close(); err != nil {
+			logEvent("close_failed", fields{"closer": fmt.Sprintf("%T", c), "error": err}, "PID %d. Failed to close %T on shutdown: %v.", pid, c, err)
+		}
+	})
+}
+
+// InitiateShutdown shuts the process down from application code, e.g. after
+// discovering a fatal configuration error at runtime. It takes exactly the
+// path a received SIGTERM takes: listeners are notified, the force-quit
+// delay is honored and the process exits with status 0, or the code set by
+// the hooks registered with OnShutdownWithCode. It is only killed, with the
+// force-quit signal, if the listeners outlive the delay.
+// It only returns if CancelShutdown aborts the shutdown, or right away if a
+// shutdown triggered by a signal or another call is already in progress.
+func InitiateShutdown() {
+	logEvent("shutdown_initiated", nil, "PID %d. Shutdown initiated by the application.", pid)
+	shutdownAndExit(syscall.SIGTERM)
+}
+
+// CancelShutdown aborts a Shutdown that is waiting out its force-quit delay,
+// e.g. when an interactive tool asks for confirmation after Ctrl-C and the
+// user declines. The aborted Shutdown returns ErrShutdownCancelled instead
+// of killing the process, and a shutdown triggered by a signal or by
+// InitiateShutdown does not exit. It reports whether a shutdown was aborted;
+// it is false without a force-quit delay, as well as once the kill has
+// started, which cannot be aborted.
+//
+// Aborting only prevents the kill: listeners already notified and commands
+// run by Exec already stopped are not undone, and the channel returned by
+// Done stays closed.
+func CancelShutdown() bool {
+	abortMu.Lock()
+	defer abortMu.Unlock()
+	if abortShutdown == nil {
+		return false
+	}
+	close(abortShutdown)
+	abortShutdown = nil
+	return true
 }
 
 // Shutdown performs a graceful shutdown by notifying all registered signal
 // listeners and optionally waiting for a configured delay before force killing.
 //
 // If delayTimeBeforeForceQuit > 0, it will:
-//  1. Send SIGTERM to all registered listeners in a goroutine
-//  2. Wait for delayTimeBeforeForceQuit duration
-//  3. Force kill the process if still alive
+//  1. Notify the listeners of sig and SIGTERM in a goroutine
+//  2. Wait for the listeners to return, for at most
+//     delayTimeBeforeForceQuit, unless CancelShutdown aborts the wait, in
+//     which case ErrShutdownCancelled is returned. If they return in time,
+//     the process is killed with sig and the steps below are skipped
+//  3. Kill the process groups of the commands still running in Exec and
+//     wait for the delay set by SetChildKillDelay, if there were any
+//  4. Run the function set by OnForceKill, if any
+//  5. Force kill the process if still alive, with the signal set by
+//     SetKillSignal (SIGKILL by default) rather than sig, which the
+//     process might handle or ignore
 //
 // If delayTimeBeforeForceQuit == 0, it will:
-//  1. Send SIGTERM to all registered listeners synchronously
-//  2. Immediately kill the process
+//  1. Notify the listeners of sig and SIGTERM synchronously, waiting for
+//     every one of them to return
+//  2. Wait for the delay set by SetSettleDelay, 0 by default
+//  3. Kill the process with sig
+//
+// The SIGTERM listeners are notified whatever sig is, since the shutdown
+// hooks of the package, e.g. CloseOnShutdown, register there.
+//
+// Commands still running in Exec are stopped as part of notifying them:
+// by default they are stopped and waited for before the listeners are
+// notified, see SetShutdownOrder.
+//
+// If a watchdog is configured via SetWatchdog, it is armed before anything
+// else happens, and the channel returned by Done is closed. How long the
+// phases took is logged and reported to the observer set by
+// SetShutdownObserver before the kill.
 func Shutdown(sig syscall.Signal) error {
-	debugf("Got signal %d, shutting down...", sig)
+	return shutdown(sig, killFn, true, false)
+}
 
-	if delayTimeBeforeForceQuit > 0 {
-		go Notify(syscall.SIGTERM)
-		time.Sleep(delayTimeBeforeForceQuit)
-		debugf("Still alive after %v, going to force kill the process...", delayTimeBeforeForceQuit)
-	} else {
-		Notify(syscall.SIGTERM)
+// ShutdownOptions configures a shutdown run by ShutdownNow.
+type ShutdownOptions struct {
+	// Signal is the signal the process is killed with when there is no
+	// force-quit delay. If 0, defaults to SIGTERM.
+	Signal syscall.Signal
+	// Kill, if set, replaces the function set by SetKillFunc for this
+	// shutdown only, e.g. a no-op in a test that must survive it.
+	Kill func(sig syscall.Signal) error
+}
+
+// ShutdownNow runs the same graceful shutdown as Shutdown and returns the
+// error of the kill step, without ever exiting the process, unlike a
+// shutdown triggered by a signal or by InitiateShutdown. It lets tests
+// exercise the whole shutdown end to end, and embedders drive it
+// programmatically, with per-call options instead of package-wide
+// settings. As the process is expected to outlive it, the watchdog set by
+// SetWatchdog is not armed.
+func ShutdownNow(opts ShutdownOptions) error {
+	kill := killFn
+	if opts.Kill != nil {
+		kill = opts.Kill
+	}
+	return shutdown(cmp.Or(opts.Signal, syscall.SIGTERM), kill, false, false)
+}
+
+// shutdown implements Shutdown, killing the process with kill and arming
+// the watchdog if watch is set. If exiting is set, the caller exits the
+// process right after, so it is only killed when forced to: the kill would
+// otherwise end it before the exit code is set, e.g. when no handler
+// catches sig or on Windows.
+func shutdown(sig syscall.Signal, kill func(syscall.Signal) error, watch, exiting bool) error {
+	if watch {
+		armWatchdog()
+	}
+	beginShutdown()
+	logEvent("shutdown_started", fields{"signal": sig}, "Got signal %d, shutting down...", sig)
+
+	start := time.Now()
+	var stats ShutdownStats
+
+	// read the delay once so a concurrent SetTimeToForceQuit cannot make
+	// this shutdown wait for one duration and report another
+	if delay := TimeToForceQuit(); delay > 0 {
+		finished := make(chan ShutdownStats, 1)
+		go func() {
+			children, listeners := notifyShutdown(sig)
+			finished <- ShutdownStats{Children: children, Listeners: listeners, Finished: true}
+		}()
+		abort := make(chan struct{})
+		abortMu.Lock()
+		abortShutdown = abort
+		abortMu.Unlock()
+
+		timer := time.NewTimer(delay)
+		forced := false
+		select {
+		case <-timer.C:
+			forced = true
+		case <-abort:
+			timer.Stop()
+		case stats = <-finished:
+			timer.Stop()
+		}
+		// past this point the kill can no longer be aborted
+		abortMu.Lock()
+		aborted := abortShutdown != abort
+		if !aborted {
+			abortShutdown = nil
+		}
+		abortMu.Unlock()
+		if aborted {
+			disarmWatchdog()
+			elapsed := time.Since(start)
+			logEvent("shutdown_cancelled", fields{"elapsed": elapsed}, "Shutdown cancelled after %v.", elapsed)
+			return ErrShutdownCancelled
+		}
+
+		if !forced {
+			// the listeners returned in time, no need to force anything
+			stats.Total = time.Since(start)
+			observeShutdown(stats)
+			if exiting {
+				return nil
+			}
+			return kill(sig)
+		}
+
+		select {
+		case stats = <-finished:
+		default:
+		}
+		stats.Total = time.Since(start)
+		observeShutdown(stats)
+		logEvent("force_quit", fields{"delay": delay}, "Still alive after %v, going to force kill the process...", delay)
+		if killChildren() {
+			time.Sleep(time.Duration(childKillDelay.Load()))
+		}
+		if fn := forceKillHook.Load(); fn != nil {
+			func() {
+				defer recovery()
+				(*fn)()
+			}()
+		}
+		return kill(forceKillSignal())
 	}
 
-	return killFn(sig)
+	stats.Children, stats.Listeners = notifyShutdown(sig)
+	stats.Finished = true
+	time.Sleep(time.Duration(settleDelay.Load()))
+	stats.Total = time.Since(start)
+	observeShutdown(stats)
+	if exiting {
+		return nil
+	}
+	return kill(sig)
+}
+
+// observeShutdown logs stats and passes them to the observer set by
+// SetShutdownObserver.
+func observeShutdown(stats ShutdownStats) {
+	logEvent("shutdown_finished", fields{
+		"total":     stats.Total,
+		"children":  stats.Children,
+		"listeners": stats.Listeners,
+		"finished":  stats.Finished,
+	}, "Shutdown took %v: children %v, listeners %v, finished %v.",
+		stats.Total, stats.Children, stats.Listeners, stats.Finished)
+	if fn := shutdownObserver.Load(); fn != nil {
+		func() {
+			defer recovery()
+			(*fn)(stats)
+		}()
+	}
 }
@@ -1,6 +1,12 @@
 package proc
 
 import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"testing"
@@ -38,14 +44,56 @@ func TestShutdown_Immediate_NotifiesAndKills(t *testing.T) {
 	}
 }
 
+func TestShutdown_Immediate_ListenersFinishBeforeKill(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	SetTimeToForceQuit(0)
+
+	const n = 20
+	var finished, atKill atomic.Int32
+	for i := range n {
+		Once(syscall.SIGTERM, func() {
+			time.Sleep(time.Duration(i%4) * time.Millisecond)
+			finished.Add(1)
+		})
+	}
+	// work a listener hands off is only awaited with a settle delay
+	var handedOff, handedOffAtKill atomic.Bool
+	Once(syscall.SIGTERM, func() {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			handedOff.Store(true)
+		}()
+	})
+	SetSettleDelay(200 * time.Millisecond)
+	defer SetSettleDelay(0)
+	killFn = func(sig syscall.Signal) error {
+		atKill.Store(finished.Load())
+		handedOffAtKill.Store(handedOff.Load())
+		return nil
+	}
+
+	if err := Shutdown(syscall.SIGTERM); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if got := atKill.Load(); got != n {
+		t.Fatalf("%d of %d listeners had returned at the kill", got, n)
+	}
+	if !handedOffAtKill.Load() {
+		t.Fatal("handed-off work had not finished within the settle delay at the kill")
+	}
+}
+
 func TestShutdown_Delayed_WaitsAndKills(t *testing.T) {
 	oldKill := killFn
 	defer func() { killFn = oldKill }()
 
 	var called int32
+	var gotSig syscall.Signal
 	killCh := make(chan struct{}, 1)
 	killFn = func(sig syscall.Signal) error {
 		atomic.AddInt32(&called, 1)
+		gotSig = sig
 		killCh <- struct{}{}
 		return nil
 	}
@@ -53,9 +101,12 @@ func TestShutdown_Delayed_WaitsAndKills(t *testing.T) {
 	delay := 60 * time.Millisecond
 	SetTimeToForceQuit(delay)
 
-	// observe notify fired too (optional)
+	// observe notify fired too, and outlive the delay
 	done := make(chan struct{}, 1)
-	Once(syscall.SIGTERM, func() { done <- struct{}{} })
+	Once(syscall.SIGTERM, func() {
+		done <- struct{}{}
+		time.Sleep(2 * delay)
+	})
 
 	start := time.Now()
 	if err := Shutdown(syscall.SIGTERM); err != nil {
@@ -75,6 +126,9 @@ func TestShutdown_Delayed_WaitsAndKills(t *testing.T) {
 	if atomic.LoadInt32(&called) != 1 {
 		t.Fatalf("killFn should be called once, got %d", called)
 	}
+	if gotSig != syscall.SIGKILL {
+		t.Fatalf("force kill used %v, want SIGKILL", gotSig)
+	}
 	select {
 	case <-done:
 		// ok
@@ -87,6 +141,92 @@ func TestShutdown_Delayed_WaitsAndKills(t *testing.T) {
 	SetTimeToForceQuit(0)
 }
 
+func TestShutdown_Delayed_ListenersFinishEarly(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	defer SetTimeToForceQuit(0)
+	var gotSig syscall.Signal
+	killFn = func(sig syscall.Signal) error {
+		gotSig = sig
+		return nil
+	}
+
+	var term, intr atomic.Bool
+	termID := On(syscall.SIGTERM, func() { term.Store(true) })
+	defer Cancel(termID)
+	intrID := On(syscall.SIGINT, func() { intr.Store(true) })
+	defer Cancel(intrID)
+
+	SetTimeToForceQuit(10 * time.Second)
+	start := time.Now()
+	if err := Shutdown(syscall.SIGINT); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Shutdown took %v, want it to return once the listeners did", elapsed)
+	}
+	if gotSig != syscall.SIGINT {
+		t.Fatalf("kill used %v, want the received SIGINT", gotSig)
+	}
+	if !term.Load() || !intr.Load() {
+		t.Fatalf("SIGTERM listener notified %v, SIGINT listener %v; want both", term.Load(), intr.Load())
+	}
+}
+
+func TestOnForceKill(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	defer SetTimeToForceQuit(0)
+	defer OnForceKill(nil)
+
+	var steps []string
+	killFn = func(sig syscall.Signal) error {
+		steps = append(steps, "kill")
+		return nil
+	}
+	OnForceKill(func() { steps = append(steps, "hook") })
+
+	SetTimeToForceQuit(0)
+	if err := Shutdown(syscall.SIGTERM); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if !slices.Equal(steps, []string{"kill"}) {
+		t.Fatalf("steps without a delay = %q, want the hook skipped", steps)
+	}
+
+	// listeners returning within the delay need no force
+	steps = nil
+	SetTimeToForceQuit(time.Second)
+	if err := Shutdown(syscall.SIGTERM); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if !slices.Equal(steps, []string{"kill"}) {
+		t.Fatalf("steps with listeners finishing early = %q, want the hook skipped", steps)
+	}
+
+	// a listener outliving the delay forces the kill
+	id := On(syscall.SIGTERM, func() { time.Sleep(30 * time.Millisecond) })
+	defer Cancel(id)
+	steps = nil
+	SetTimeToForceQuit(10 * time.Millisecond)
+	if err := Shutdown(syscall.SIGTERM); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if !slices.Equal(steps, []string{"hook", "kill"}) {
+		t.Fatalf("steps with a delay = %q, want the hook right before the kill", steps)
+	}
+
+	// a panicking hook does not prevent the kill
+	steps = nil
+	OnForceKill(func() { panic("boom") })
+	if err := Shutdown(syscall.SIGTERM); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if !slices.Equal(steps, []string{"kill"}) {
+		t.Fatalf("steps with a panicking hook = %q, want the kill", steps)
+	}
+}
+
 func TestShutdown_KillError(t *testing.T) {
 	// Test that Shutdown returns error if kill fails
 	oldKill := killFn
@@ -105,16 +245,78 @@ func TestShutdown_KillError(t *testing.T) {
 	}
 }
 
+func TestTimeToForceQuit_Default(t *testing.T) {
+	// every test restores the delay to 0, the package default
+	if d := TimeToForceQuit(); d != 0 {
+		t.Fatalf("TimeToForceQuit() = %v, want 0 by default", d)
+	}
+	defer SetTimeToForceQuit(0)
+	SetDefaultTimeToForceQuit()
+	if d := TimeToForceQuit(); d != 5500*time.Millisecond {
+		t.Fatalf("TimeToForceQuit() after SetDefaultTimeToForceQuit = %v, want 5.5s", d)
+	}
+}
+
 func TestSetTimeToForceQuit(t *testing.T) {
 	// Test that SetTimeToForceQuit updates the delay
-	oldDelay := delayTimeBeforeForceQuit
-	defer func() { delayTimeBeforeForceQuit = oldDelay }()
+	oldDelay := TimeToForceQuit()
+	defer SetTimeToForceQuit(oldDelay)
 
 	newDelay := 123 * time.Millisecond
 	SetTimeToForceQuit(newDelay)
 
-	if delayTimeBeforeForceQuit != newDelay {
-		t.Fatalf("Expected delay %v, got %v", newDelay, delayTimeBeforeForceQuit)
+	if TimeToForceQuit() != newDelay {
+		t.Fatalf("Expected delay %v, got %v", newDelay, TimeToForceQuit())
+	}
+}
+
+func TestSetTimeToForceQuitString(t *testing.T) {
+	defer SetTimeToForceQuit(0)
+
+	if err := SetTimeToForceQuitString("5s500ms"); err != nil {
+		t.Fatalf("SetTimeToForceQuitString failed: %v", err)
+	}
+	if d := TimeToForceQuit(); d != 5500*time.Millisecond {
+		t.Fatalf("TimeToForceQuit() = %v, want 5.5s", d)
+	}
+	if err := SetTimeToForceQuitString(TimeToForceQuit().String()); err != nil || TimeToForceQuit() != 5500*time.Millisecond {
+		t.Fatalf("round trip gave %v, %v", TimeToForceQuit(), err)
+	}
+
+	for _, bad := range []string{"", "5", "soon", "-1s"} {
+		if err := SetTimeToForceQuitString(bad); err == nil {
+			t.Fatalf("SetTimeToForceQuitString(%q) succeeded, want an error", bad)
+		}
+		if d := TimeToForceQuit(); d != 5500*time.Millisecond {
+			t.Fatalf("SetTimeToForceQuitString(%q) changed the delay to %v", bad, d)
+		}
+	}
+}
+
+func TestExtendForceQuit(t *testing.T) {
+	defer SetTimeToForceQuit(0)
+
+	outer := ExtendForceQuit(time.Second)
+	if d := TimeToForceQuit(); d != 0 {
+		t.Fatalf("TimeToForceQuit() = %v, want a delay of 0 left unextended", d)
+	}
+	SetTimeToForceQuit(100 * time.Millisecond)
+	if d := TimeToForceQuit(); d != 1100*time.Millisecond {
+		t.Fatalf("TimeToForceQuit() = %v, want 1.1s", d)
+	}
+	inner := ExtendForceQuit(2 * time.Second)
+	if d := TimeToForceQuit(); d != 3100*time.Millisecond {
+		t.Fatalf("TimeToForceQuit() with nested extensions = %v, want 3.1s", d)
+	}
+
+	outer()
+	outer()
+	if d := TimeToForceQuit(); d != 2100*time.Millisecond {
+		t.Fatalf("TimeToForceQuit() after restoring the outer extension twice = %v, want 2.1s", d)
+	}
+	inner()
+	if d := TimeToForceQuit(); d != 100*time.Millisecond {
+		t.Fatalf("TimeToForceQuit() after restoring all = %v, want 100ms", d)
 	}
 }
 
@@ -153,3 +355,522 @@ func TestShutdown_MultipleListeners(t *testing.T) {
 		t.Fatalf("listener 3 should be notified once, got %d", count3)
 	}
 }
+
+func TestShutdown_WatchdogExits(t *testing.T) {
+	oldKill, oldExit, oldLogger := killFn, exitFn, Logger
+	defer func() { killFn, exitFn, Logger = oldKill, oldExit, oldLogger }()
+	defer disarmWatchdog()
+
+	killFn = func(sig syscall.Signal) error { return nil }
+	exited := make(chan int, 1)
+	exitFn = func(code int) { exited <- code }
+	var buf syncBuffer
+	Logger = &buf
+
+	SetTimeToForceQuit(0)
+	SetWatchdog(20 * time.Millisecond)
+	defer SetWatchdog(0)
+
+	if err := Shutdown(syscall.SIGTERM); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Fatalf("watchdog exit code = %d, want 1", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watchdog did not fire")
+	}
+	if !strings.Contains(buf.String(), "goroutine ") {
+		t.Fatalf("watchdog should log a stack dump, got: %q", buf.String())
+	}
+}
+
+func TestShutdown_WatchdogDisabledByDefault(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	killFn = func(sig syscall.Signal) error { return nil }
+
+	SetTimeToForceQuit(0)
+	if err := Shutdown(syscall.SIGTERM); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	watchdogMu.Lock()
+	armed := watchdogTimer != nil
+	watchdogMu.Unlock()
+	if armed {
+		t.Fatal("watchdog should not be armed when not configured")
+	}
+}
+
+func TestCancelShutdown_DisarmsWatchdog(t *testing.T) {
+	oldKill, oldExit := killFn, exitFn
+	defer func() { killFn, exitFn = oldKill, oldExit }()
+	defer disarmWatchdog()
+	killFn = func(syscall.Signal) error { return nil }
+	var exited atomic.Bool
+	exitFn = func(int) { exited.Store(true) }
+
+	release := make(chan struct{})
+	defer close(release)
+	id := On(syscall.SIGTERM, func() { <-release })
+	defer Cancel(id)
+
+	SetTimeToForceQuit(10 * time.Second)
+	defer SetTimeToForceQuit(0)
+	SetWatchdog(100 * time.Millisecond)
+	defer SetWatchdog(0)
+
+	errc := make(chan error, 1)
+	go func() { errc <- Shutdown(syscall.SIGTERM) }()
+	deadline := time.Now().Add(time.Second)
+	for !CancelShutdown() {
+		if time.Now().After(deadline) {
+			t.Fatal("CancelShutdown never found the pending shutdown")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := <-errc; !errors.Is(err, ErrShutdownCancelled) {
+		t.Fatalf("Shutdown = %v, want ErrShutdownCancelled", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if exited.Load() {
+		t.Fatal("watchdog exited the process after the shutdown was cancelled")
+	}
+}
+
+func TestSetKillFunc(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+
+	var gotSig syscall.Signal
+	SetKillFunc(func(sig syscall.Signal) error {
+		gotSig = sig
+		return nil
+	})
+	SetTimeToForceQuit(0)
+
+	if err := Shutdown(syscall.SIGINT); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if gotSig != syscall.SIGINT {
+		t.Fatalf("custom kill func got sig=%v, want SIGINT", gotSig)
+	}
+
+	SetKillFunc(nil)
+	if reflect.ValueOf(killFn).Pointer() != reflect.ValueOf(kill).Pointer() {
+		t.Fatal("SetKillFunc(nil) should restore the default kill function")
+	}
+}
+
+func TestInitiateShutdown(t *testing.T) {
+	oldKill, oldExit := killFn, exitFn
+	defer func() { killFn, exitFn = oldKill, oldExit }()
+
+	var steps []string
+	killFn = func(sig syscall.Signal) error {
+		steps = append(steps, "kill "+sig.String())
+		return nil
+	}
+	exitFn = func(code int) { steps = append(steps, "exit "+strconv.Itoa(code)) }
+	SetTimeToForceQuit(0)
+
+	Once(syscall.SIGTERM, func() { steps = append(steps, "notify") })
+
+	InitiateShutdown()
+
+	// the process exits right after, so it is not killed first
+	want := []string{"notify", "exit 0"}
+	if !slices.Equal(steps, want) {
+		t.Fatalf("shutdown steps = %q, want %q", steps, want)
+	}
+}
+
+func TestOnShutdownWithCode(t *testing.T) {
+	oldKill, oldExit := killFn, exitFn
+	defer func() { killFn, exitFn = oldKill, oldExit }()
+
+	var killSig syscall.Signal
+	killFn = func(sig syscall.Signal) error { killSig = sig; return nil }
+	code := -1
+	exitFn = func(c int) { code = c }
+	SetTimeToForceQuit(0)
+	defer SetTimeToForceQuit(0)
+
+	var seen []int
+	defer OnShutdownWithCode(func(c int) int { seen = append(seen, c); return 3 })()
+	defer OnShutdownWithCode(func(c int) int { panic("boom") })()
+	removed := OnShutdownWithCode(func(c int) int { return 99 })
+	defer OnShutdownWithCode(func(c int) int { seen = append(seen, c); return c + 1 })()
+	removed()
+
+	InitiateShutdown()
+
+	if code != 4 {
+		t.Fatalf("exit code = %d, want 4", code)
+	}
+	if !slices.Equal(seen, []int{0, 3}) {
+		t.Fatalf("hooks saw codes %v, want [0 3]", seen)
+	}
+
+	// with a force-quit delay the listeners return early, so the process
+	// is not force-killed and lives on to run the hooks
+	code, seen = -1, nil
+	SetTimeToForceQuit(time.Second)
+	InitiateShutdown()
+	if killSig != 0 {
+		t.Fatalf("process killed with %v before exiting, want no kill", killSig)
+	}
+	if code != 4 || !slices.Equal(seen, []int{0, 3}) {
+		t.Fatalf("with a delay, exit code = %d and hooks saw %v; want 4 and [0 3]", code, seen)
+	}
+}
+
+func TestOnShutdownWithCode_RealKill(t *testing.T) {
+	// with the real kill and no handler for SIGTERM, as with PROC_NOSIGNAL,
+	// killing the process before exiting would end the test binary
+	StopSignalHandling()
+	defer StartSignalHandling()
+	oldKill, oldExit := killFn, exitFn
+	defer func() { killFn, exitFn = oldKill, oldExit }()
+	SetKillFunc(nil)
+	code := -1
+	exitFn = func(c int) { code = c }
+	SetTimeToForceQuit(0)
+
+	defer OnShutdownWithCode(func(int) int { return 7 })()
+	InitiateShutdown()
+	time.Sleep(50 * time.Millisecond) // a stray SIGTERM would land by now
+	if code != 7 {
+		t.Fatalf("exit code = %d, want 7", code)
+	}
+}
+
+func TestShutdown_ConcurrentSetTimeToForceQuit(t *testing.T) {
+	// Run with -race: updating the delay while a shutdown reads it must not
+	// be reported as a data race.
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	killFn = func(sig syscall.Signal) error { return nil }
+	defer SetTimeToForceQuit(0)
+
+	SetTimeToForceQuit(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 50 {
+			SetTimeToForceQuit(time.Duration(i%3) * time.Millisecond)
+		}
+	}()
+
+	if err := Shutdown(syscall.SIGTERM); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	<-done
+}
+
+func TestOnShutdownTimeout_AbandonsSlowHook(t *testing.T) {
+	var buf syncBuffer
+	old := Logger
+	Logger = &buf
+	defer func() { Logger = old }()
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := OnShutdownTimeout(50*time.Millisecond, func(context.Context) error {
+		<-release // ignores its context
+		return nil
+	})
+	var ctxLive atomic.Bool
+	fast := OnShutdownTimeout(time.Second, func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		ctxLive.Store(ok && ctx.Err() == nil)
+		return errors.New("flush failed")
+	})
+	defer Cancel(slow, fast)
+
+	start := time.Now()
+	Notify(syscall.SIGTERM)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Notify took %v, want the slow hook abandoned after 50ms", elapsed)
+	}
+	if !ctxLive.Load() {
+		t.Fatal("hook should get a live context with a deadline")
+	}
+	out := buf.String()
+	if !strings.Contains(out, "abandoning") || !strings.Contains(out, "flush failed") {
+		t.Fatalf("log = %q, want the abandoned hook and the hook error", out)
+	}
+}
+
+func TestSetKillSignal(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	var gotSig syscall.Signal
+	killFn = func(sig syscall.Signal) error {
+		gotSig = sig
+		return nil
+	}
+	defer SetTimeToForceQuit(0)
+	defer SetKillSignal(0)
+	id := On(syscall.SIGTERM, func() { time.Sleep(10 * time.Millisecond) })
+	defer Cancel(id)
+
+	SetTimeToForceQuit(time.Millisecond)
+	SetKillSignal(syscall.SIGQUIT)
+	Shutdown(syscall.SIGINT)
+	if gotSig != syscall.SIGQUIT {
+		t.Fatalf("force kill used %v, want SIGQUIT", gotSig)
+	}
+
+	SetKillSignal(0)
+	Shutdown(syscall.SIGINT)
+	if gotSig != syscall.SIGKILL {
+		t.Fatalf("force kill after reset used %v, want SIGKILL", gotSig)
+	}
+
+	// without a delay, the process is killed with the given signal
+	SetTimeToForceQuit(0)
+	Shutdown(syscall.SIGINT)
+	if gotSig != syscall.SIGINT {
+		t.Fatalf("immediate kill used %v, want SIGINT", gotSig)
+	}
+}
+
+func TestSetShutdownObserver(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	killFn = func(syscall.Signal) error { return nil }
+	defer SetTimeToForceQuit(0)
+
+	var got []ShutdownStats
+	SetShutdownObserver(func(s ShutdownStats) { got = append(got, s) })
+	defer SetShutdownObserver(nil)
+
+	id := On(syscall.SIGTERM, func() { time.Sleep(20 * time.Millisecond) })
+	defer Cancel(id)
+
+	// listeners finish well within the delay
+	SetTimeToForceQuit(100 * time.Millisecond)
+	Shutdown(syscall.SIGTERM)
+	// the delay elapses before the listeners return
+	SetTimeToForceQuit(5 * time.Millisecond)
+	Shutdown(syscall.SIGTERM)
+	// no delay
+	SetTimeToForceQuit(0)
+	Shutdown(syscall.SIGTERM)
+
+	if len(got) != 3 {
+		t.Fatalf("observer called %d times, want 3", len(got))
+	}
+	if s := got[0]; !s.Finished || s.Listeners < 20*time.Millisecond || s.Total < s.Listeners || s.Total >= 100*time.Millisecond {
+		t.Fatalf("stats with delay = %+v, want finished listeners >= 20ms within a total < 100ms", s)
+	}
+	if s := got[1]; s.Finished || s.Listeners != 0 {
+		t.Fatalf("stats with elapsed delay = %+v, want unfinished", s)
+	}
+	if s := got[2]; !s.Finished || s.Listeners < 20*time.Millisecond || s.Total < s.Listeners {
+		t.Fatalf("stats without delay = %+v, want finished listeners >= 20ms", s)
+	}
+	time.Sleep(30 * time.Millisecond) // let the abandoned listener finish
+}
+
+func TestSetShutdownObserver_Panic(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	var killed bool
+	killFn = func(syscall.Signal) error { killed = true; return nil }
+	SetTimeToForceQuit(0)
+
+	SetShutdownObserver(func(ShutdownStats) { panic("boom") })
+	defer SetShutdownObserver(nil)
+
+	if err := Shutdown(syscall.SIGTERM); err != nil || !killed {
+		t.Fatalf("Shutdown = %v, killed = %v; want nil and killed despite the panicking observer", err, killed)
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestCloseOnShutdown(t *testing.T) {
+	var buf syncBuffer
+	old := Logger
+	Logger = &buf
+	defer func() { Logger = old }()
+
+	var closed, cancelled atomic.Int32
+	ok := CloseOnShutdown(closerFunc(func() error {
+		closed.Add(1)
+		return nil
+	}))
+	failing := CloseOnShutdown(closerFunc(func() error {
+		closed.Add(1)
+		return errors.New("already closed")
+	}))
+	gone := CloseOnShutdown(closerFunc(func() error {
+		cancelled.Add(1)
+		return nil
+	}))
+	defer Cancel(ok, failing)
+	Cancel(gone)
+
+	Notify(syscall.SIGTERM)
+	Notify(syscall.SIGTERM)
+	if n := closed.Load(); n != 2 {
+		t.Fatalf("Close called %d times, want 2", n)
+	}
+	if cancelled.Load() != 0 {
+		t.Fatal("cancelled closer should not be closed")
+	}
+	if !strings.Contains(buf.String(), "already closed") {
+		t.Fatalf("log = %q, want the close error", buf.String())
+	}
+}
+
+func TestDone_IsShuttingDown(t *testing.T) {
+	oldKill := killFn
+	defer func() { killFn = oldKill }()
+	killFn = func(syscall.Signal) error { return nil }
+	shuttingDown.Store(false)
+	shutdownDone = make(chan struct{})
+
+	done := Done()
+	select {
+	case <-done:
+		t.Fatal("Done closed before a shutdown")
+	default:
+	}
+
+	if IsShuttingDown() {
+		t.Fatal("IsShuttingDown before a shutdown")
+	}
+
+	var closedFirst atomic.Bool
+	id := On(syscall.SIGTERM, func() {
+		select {
+		case <-done:
+			closedFirst.Store(IsShuttingDown())
+		default:
+		}
+	})
+	defer Cancel(id)
+
+	Shutdown(syscall.SIGTERM)
+	Shutdown(syscall.SIGTERM) // must not close it twice
+	select {
+	case <-done:
+	default:
+		t.Fatal("Done not closed after Shutdown")
+	}
+	if !closedFirst.Load() {
+		t.Fatal("Done should be closed and IsShuttingDown true before the listeners are notified")
+	}
+}
+
+func TestCancelShutdown(t *testing.T) {
+	oldKill, oldExit := killFn, exitFn
+	defer func() { killFn, exitFn = oldKill, oldExit }()
+	var killed, exited atomic.Bool
+	killFn = func(syscall.Signal) error { killed.Store(true); return nil }
+	exitFn = func(int) { exited.Store(true) }
+	defer SetTimeToForceQuit(0)
+
+	if CancelShutdown() {
+		t.Fatal("CancelShutdown without a pending shutdown should return false")
+	}
+
+	// a listener outliving the delay keeps the shutdown pending
+	release := make(chan struct{})
+	defer close(release)
+	id := On(syscall.SIGTERM, func() { <-release })
+	defer Cancel(id)
+
+	SetTimeToForceQuit(10 * time.Second)
+	done := make(chan struct{})
+	go func() {
+		InitiateShutdown()
+		close(done)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for !CancelShutdown() {
+		if time.Now().After(deadline) {
+			t.Fatal("CancelShutdown never found the pending shutdown")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("InitiateShutdown did not return after CancelShutdown")
+	}
+	if killed.Load() || exited.Load() {
+		t.Fatalf("cancelled shutdown killed=%v exited=%v, want neither", killed.Load(), exited.Load())
+	}
+	if CancelShutdown() {
+		t.Fatal("CancelShutdown should return false once the shutdown was aborted")
+	}
+
+	// once the delay has elapsed, the kill goes ahead
+	SetTimeToForceQuit(time.Millisecond)
+	if err := Shutdown(syscall.SIGTERM); err != nil || !killed.Load() {
+		t.Fatalf("Shutdown = %v, killed = %v; want nil and killed", err, killed.Load())
+	}
+	if CancelShutdown() {
+		t.Fatal("CancelShutdown should return false after the kill")
+	}
+}
+
+func TestShutdownNow(t *testing.T) {
+	oldKill, oldExit := killFn, exitFn
+	defer func() { killFn, exitFn = oldKill, oldExit }()
+	killFn = func(syscall.Signal) error {
+		t.Fatal("ShutdownNow should use ShutdownOptions.Kill")
+		return nil
+	}
+	exitFn = func(int) { t.Fatal("ShutdownNow must not exit") }
+	SetTimeToForceQuit(0)
+
+	var steps []string
+	id := Once(syscall.SIGTERM, func() { steps = append(steps, "notify") })
+	defer Cancel(id)
+
+	err := ShutdownNow(ShutdownOptions{
+		Kill: func(sig syscall.Signal) error {
+			steps = append(steps, "kill "+sig.String())
+			return errors.New("kill failed")
+		},
+	})
+	if err == nil || err.Error() != "kill failed" {
+		t.Fatalf("ShutdownNow = %v, want the kill error", err)
+	}
+	want := []string{"notify", "kill " + syscall.SIGTERM.String()}
+	if !slices.Equal(steps, want) {
+		t.Fatalf("shutdown steps = %q, want %q", steps, want)
+	}
+}
+
+func TestShutdownNow_SkipsWatchdog(t *testing.T) {
+	oldExit := exitFn
+	defer func() { exitFn = oldExit }()
+	defer disarmWatchdog()
+	var exited atomic.Bool
+	exitFn = func(int) { exited.Store(true) }
+	SetTimeToForceQuit(0)
+	SetWatchdog(20 * time.Millisecond)
+	defer SetWatchdog(0)
+
+	if err := ShutdownNow(ShutdownOptions{Kill: func(syscall.Signal) error { return nil }}); err != nil {
+		t.Fatalf("ShutdownNow failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if exited.Load() {
+		t.Fatal("watchdog exited the process after ShutdownNow")
+	}
+}
@@ -1,6 +1,9 @@
 package proc
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"runtime/debug"
@@ -8,6 +11,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 )
 
 var (
@@ -21,53 +25,337 @@ var (
 	mask uint32
 	// sigch is the channel that receives OS signals
 	sigch chan os.Signal
+	// sigstop stops the signal handling goroutine, nil while stopped
+	sigstop chan struct{}
+	// sigdone is closed once the signal handling goroutine has exited
+	sigdone chan struct{}
+	// events receives every signal dispatched by Notify, see Events
+	events = make(chan os.Signal, eventsBuffer)
+	// suspended counts the active Suspend calls per signal number
+	suspended [numSig]int
+	// pending marks the signal numbers dispatched while suspended
+	pending [numSig]bool
+	// lastNotified holds the IDs of the listeners each signal number was
+	// last dispatched to, see LastNotified
+	lastNotified [numSig][]uint32
+	// listenerHook is the hook set by SetListenerEventHook, nil if none
+	listenerHook atomic.Pointer[func(event string, id uint32, sig os.Signal)]
+	// defaultHandler is the handler set by SetDefaultHandler, nil if none
+	defaultHandler atomic.Pointer[func(os.Signal)]
+	// lastSig and lastSigAt record the most recent signal received from the
+	// OS, see LastSignal
+	lastSig   os.Signal
+	lastSigAt time.Time
+	// shutdownRunning reports whether shutdownAndExit is in progress
+	shutdownRunning atomic.Bool
+	// maxListeners is the limit set by SetMaxListeners, 0 meaning none
+	maxListeners int
+	// notifyConcurrency is the limit set by SetNotifyConcurrency, 0
+	// meaning none
+	notifyConcurrency atomic.Int32
+	// shutdownSigs holds the signals that trigger a graceful shutdown
+	shutdownSigs = []os.Signal{
+		syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM,
+	}
 )
 
-// registerSignalListener initializes the signal handling system.
-// It creates a signal channel and starts a goroutine to handle incoming signals.
-// The following signals are handled:
-// - SIGHUP, SIGINT, SIGQUIT, SIGTERM: Trigger graceful shutdown
-// - Other signals: Dispatched to registered listeners
+// eventsBuffer is the capacity of the channel returned by Events.
+const eventsBuffer = 16
+
+// StartSignalHandling starts handling OS signals. It creates the signal
+// channel, registers it with the OS and starts a goroutine that handles
+// incoming signals:
+//   - Shutdown signals (SIGHUP, SIGINT, SIGQUIT, SIGTERM by default, see
+//     SetShutdownSignals): Trigger graceful shutdown
+//   - Other signals: Dispatched to registered listeners
+//
+// It is called on import unless the PROC_NOSIGNAL environment variable is
+// set to a non-empty value, which lets embedders opt in explicitly. Calling
+// it while signal handling is already running is a no-op, and it may be
+// called again after StopSignalHandling. The rest of the package does not
+// depend on it: while signal handling is not running, listeners can still
+// be registered and dispatched with Notify, and Shutdown works as usual;
+// only signals from the OS are not received.
 //
 // References:
 // - https://golang.org/pkg/os/signal/#Notify
 // - https://colobu.com/2015/10/09/Linux-Signals/
-func registerSignalListener() {
+func StartSignalHandling() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if sigstop != nil {
+		return
+	}
+
 	// https://golang.org/pkg/os/signal/#Notify
 	sigch = make(chan os.Signal, 1)
+	sigstop = make(chan struct{})
+	sigdone = make(chan struct{})
 
 	// https://colobu.com/2015/10/09/Linux-Signals/
-	signal.Notify(
-		sigch,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGQUIT,
-		syscall.SIGTERM,
-	)
+	// Notify without signals would relay all of them
+	if len(shutdownSigs) > 0 {
+		signal.Notify(sigch, shutdownSigs...)
+	}
 
-	go func() {
-		for {
-			sig := <-sigch
-			debugf("PID: %d. Received %v.", pid, sig)
-			switch sig {
-			case syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM:
-				// gracefully shuts down the process.
-				Shutdown(syscall.SIGTERM)
-				signal.Stop(sigch)
-				os.Exit(0)
-			default:
-				if !Notify(sig) {
-					debugf("PID %d. Got unregistered signal: %v.", pid, sig)
-				}
+	// re-register signals of listeners added while stopped
+	for _, l := range lns {
+		watch(l.sig)
+	}
+	for _, n := range earlyWatched {
+		watch(n)
+	}
+
+	go loop(sigch, sigstop, sigdone)
+}
+
+// StopSignalHandling stops handling OS signals and restores their default
+// behavior. It calls signal.Stop, waits for the handling goroutine to exit
+// and drains signals that were still pending. Registered listeners are kept
+// and can still be triggered via Notify; they receive OS signals again once
+// StartSignalHandling is called.
+//
+// It must not be called from a signal listener, as it waits for the
+// goroutine dispatching to that listener.
+func StopSignalHandling() {
+	lock.Lock()
+	if sigstop == nil {
+		lock.Unlock()
+		return
+	}
+	ch, stop, done := sigch, sigstop, sigdone
+	signal.Stop(ch)
+	sigstop, sigdone = nil, nil
+	mask = 0
+	lock.Unlock()
+
+	close(stop)
+	<-done
+
+	for {
+		select {
+		case sig := <-ch:
+			logEvent("signal_dropped", fields{"signal": sig}, "PID %d. Dropped %v after signal handling stopped.", pid, sig)
+		default:
+			return
+		}
+	}
+}
+
+// Enable is shorthand for StartSignalHandling.
+func Enable() {
+	StartSignalHandling()
+}
+
+// Disable is shorthand for StopSignalHandling.
+func Disable() {
+	StopSignalHandling()
+}
+
+// loop receives signals from ch until stop is closed, then closes done.
+func loop(ch chan os.Signal, stop, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case sig := <-ch:
+			lock.Lock()
+			lastSig, lastSigAt = sig, time.Now()
+			lock.Unlock()
+			handle(sig)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// LastSignal returns the most recent signal received from the OS by the
+// signal handling goroutine and when it arrived, e.g. for a health endpoint
+// reporting "last received SIGHUP 3m ago". The bool is false if no signal
+// has been received yet. Signals dispatched with Notify, Trigger or
+// SendAfter are not recorded.
+func LastSignal() (os.Signal, time.Time, bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	return lastSig, lastSigAt, lastSig != nil
+}
+
+// handle processes a received signal: shutdown signals gracefully shut
+// down and exit the process, other signals are dispatched to listeners.
+func handle(sig os.Signal) {
+	if IsDebug() {
+		logEvent("signal_received", fields{"signal": sig}, "PID: %d. Received %v.", pid, sig)
+	}
+	lock.Lock()
+	shutdown := slices.Contains(shutdownSigs, sig)
+	queued := !shutdown && signum(sig) > -1 && queueEarly(sig, signum(sig))
+	lock.Unlock()
+
+	if queued {
+		return
+	}
+	if !shutdown {
+		if notified, held := notify(sig); !notified && !held {
+			if fn := defaultHandler.Load(); fn != nil {
+				func() {
+					defer recovery()
+					(*fn)(sig)
+				}()
+			} else if IsDebug() {
+				logEvent("signal_unhandled", fields{"signal": sig}, "PID %d. Got unregistered signal: %v.", pid, sig)
 			}
 		}
-	}()
+		return
+	}
+
+	// shut down in the background so further signals are still received:
+	// more shutdown signals are then coalesced rather than queued up to
+	// start another shutdown, and listeners can abort it via CancelShutdown
+	go shutdownAndExit(shutdownSignal(sig))
+}
+
+// shutdownSignal returns sig as a syscall.Signal, or SIGTERM if it is not
+// one.
+func shutdownSignal(sig os.Signal) syscall.Signal {
+	if s, ok := sig.(syscall.Signal); ok {
+		return s
+	}
+	return syscall.SIGTERM
+}
+
+// shutdownAndExit gracefully shuts down on sig and exits the process. It is a
+// no-op while another call is in progress, so shutdown signals arriving
+// together, e.g. SIGTERM from an orchestrator and SIGINT from a terminal,
+// trigger a single shutdown.
+func shutdownAndExit(sig syscall.Signal) {
+	if !shutdownRunning.CompareAndSwap(false, true) {
+		logEvent("shutdown_ignored", nil, "PID %d. Already shutting down, ignoring the request.", pid)
+		return
+	}
+	// allow another shutdown should the process outlive this one, i.e.
+	// when it was cancelled
+	defer shutdownRunning.Store(false)
+
+	if errors.Is(shutdown(sig, killFn, true, true), ErrShutdownCancelled) {
+		return
+	}
+	lock.Lock()
+	if sigstop != nil {
+		signal.Stop(sigch)
+	}
+	lock.Unlock()
+	exitFn(exitCode())
+}
+
+// SetShutdownSignals replaces the set of signals that trigger a graceful
+// shutdown when received from the OS. Signals removed from the set are no
+// longer treated specially; they are dispatched to their listeners like any
+// other signal, and ignored if there are none.
+func SetShutdownSignals(sigs ...os.Signal) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	shutdownSigs = slices.Clone(sigs)
+	for _, sig := range shutdownSigs {
+		if n := signum(sig); n > -1 {
+			watch(n)
+		}
+	}
+}
+
+// ShutdownSignals returns the signals that currently trigger a graceful
+// shutdown, see SetShutdownSignals and OnReload. The result is a copy.
+func ShutdownSignals() []os.Signal {
+	lock.Lock()
+	defer lock.Unlock()
+	return slices.Clone(shutdownSigs)
+}
+
+// watch registers the signal numbered n with the OS if signal handling is
+// running and it has not been registered yet. The caller must hold lock.
+func watch(n int) {
+	// see go/src/os/signal/signal.go
+	if sigstop != nil && (mask>>uint(n&31))&1 == 0 {
+		mask |= 1 << uint(n&31)
+		signal.Notify(sigch, syscall.Signal(n))
+	}
+}
+
+// unwatch deregisters the signal numbered n from the OS, if it was
+// registered and is neither a shutdown signal nor listened for anymore. Its
+// default behavior is restored unless another package still listens for it
+// with signal.Notify. The caller must hold lock.
+func unwatch(n int) {
+	if sigstop == nil || (mask>>uint(n&31))&1 == 0 {
+		return
+	}
+	if slices.ContainsFunc(lns, func(l *listener) bool { return l.sig == n }) ||
+		slices.Contains(shutdownSigs, os.Signal(syscall.Signal(n))) {
+		return
+	}
+
+	// signal.Reset would also take the signal from other packages, so stop
+	// only sigch and register it again for the signals still watched. A
+	// temporary channel keeps those handled in between, so none of them
+	// falls back to its default behavior, e.g. terminating the process.
+	// Signals whose listeners were all cancelled are dropped as well, so
+	// the mask is rebuilt to match: a later listener must register its
+	// signal again.
+	keep := slices.Clone(shutdownSigs)
+	for _, l := range lns {
+		keep = append(keep, syscall.Signal(l.sig))
+	}
+	for _, m := range earlyWatched {
+		if m != n {
+			keep = append(keep, syscall.Signal(m))
+		}
+	}
+	mask = 0
+	for _, sig := range keep {
+		if m := signum(sig); m > -1 {
+			mask |= 1 << uint(m&31)
+		}
+	}
+	if len(keep) == 0 {
+		// Notify without signals would relay all of them
+		signal.Stop(sigch)
+		return
+	}
+	tmp := make(chan os.Signal, 1)
+	signal.Notify(tmp, keep...)
+	signal.Stop(sigch)
+	signal.Notify(sigch, keep...)
+	signal.Stop(tmp)
+	select {
+	case sig := <-tmp:
+		select {
+		case sigch <- sig:
+		default:
+		}
+	default:
+	}
 }
 
 // numSig is the maximum number of signals supported across all systems.
 // This value is defined to match the implementation in go/src/os/signal/signal.go.
 const numSig = 65
 
+// SignalNumber returns the number of sig, e.g. to persist a signal choice
+// or log it compactly. It reports false if sig is not a syscall.Signal or
+// its number is out of the range the package handles, [0, 65).
+//
+// Signal numbers are not portable: apart from a few such as SIGINT (2),
+// SIGKILL (9) and SIGTERM (15), they differ between operating systems and
+// architectures, e.g. SIGUSR1 is 10 on Linux but 30 on macOS. Prefer
+// storing the signal name when the value may cross platforms.
+func SignalNumber(sig os.Signal) (int, bool) {
+	n := signum(sig)
+	return n, n > -1
+}
+
 // signum converts an os.Signal to its numeric representation.
 // Returns -1 if the signal is not a valid syscall.Signal or is out of range.
 func signum(sig os.Signal) int {
@@ -89,6 +377,9 @@ type listener struct {
 	id uint32
 	// fn is the callback function to execute when the signal is received
 	fn func()
+	// raw is the callback as registered, before wrap made a Once listener
+	// run at most once, so Snapshot can re-arm it
+	raw func()
 	// sig is the numeric representation of the signal to listen for
 	sig int
 	// once indicates whether this listener should execute only once
@@ -102,26 +393,75 @@ type listener struct {
 func add(sig os.Signal, fn func(), once bool) uint32 {
 	if n := signum(sig); n > -1 {
 		lock.Lock()
-		defer lock.Unlock()
-
-		// see go/src/os/signal/signal.go
-		if (mask>>uint(n&31))&1 == 0 {
-			mask |= 1 << uint(n&31)
-			signal.Notify(sigch, sig)
-		}
-
+		watch(n)
 		id := atomic.AddUint32(&seq, 1)
 		lns = append(lns, &listener{
 			id:   id,
 			fn:   wrap(fn, once),
+			raw:  fn,
 			sig:  n,
 			once: once,
 		})
+		replay := takeEarly(n)
+		count, limit := len(lns), maxListeners
+		lock.Unlock()
+
+		// warn when crossing the limit rather than on every registration,
+		// so a leaking loop does not flood the log
+		if limit > 0 && count == limit+1 {
+			logEvent("listener_limit_exceeded", fields{"count": count, "limit": limit, "signal": sig},
+				"PID %d. %d signal listeners registered, more than the %d set by SetMaxListeners; possible leak, last added for %v.", pid, count, limit, sig)
+		}
+		emit("add", id, n)
+		if len(replay) > 0 {
+			go func() {
+				for _, sig := range replay {
+					Notify(sig)
+				}
+			}()
+		}
 		return id
 	}
 	return 0
 }
 
+// SetMaxListeners sets the number of registered listeners above which a
+// warning is written to Logger, to catch leaks such as Once listeners
+// registered in a loop that never fire. The warning is written each time
+// the count grows past n. If n is 0, the default, there is no limit.
+// Registration is never refused.
+func SetMaxListeners(n int) {
+	lock.Lock()
+	defer lock.Unlock()
+	maxListeners = n
+}
+
+// SetNotifyConcurrency limits how many listeners a single dispatch by
+// Notify runs at the same time to n, so a signal with hundreds of
+// listeners does not start hundreds of goroutines at once; Notify still
+// waits for all of them. Listeners waiting for each other can deadlock
+// under a limit. If n is 0, the default, there is no limit.
+func SetNotifyConcurrency(n int) {
+	notifyConcurrency.Store(int32(max(n, 0)))
+}
+
+// remove deletes the listeners matched by match and returns them if a
+// listener event hook is set, so their removal can be reported. The caller
+// must hold lock.
+func remove(match func(l *listener) bool) (removed []*listener) {
+	hooked := listenerHook.Load() != nil
+	lns = slices.DeleteFunc(lns, func(l *listener) bool {
+		if !match(l) {
+			return false
+		}
+		if hooked {
+			removed = append(removed, l)
+		}
+		return true
+	})
+	return removed
+}
+
 // wrap returns a function that optionally ensures single execution.
 // If once is true, the returned function will execute fn at most once,
 // even if called multiple times. If once is false, returns fn unchanged.
@@ -148,10 +488,63 @@ func Once(sig os.Signal, fn func()) uint32 {
 	return add(sig, fn, true)
 }
 
+// OnceTimeout is like Once but only waits for sig for ttl: if sig has not
+// arrived by then, the listener is removed without fn being called, so
+// listeners for signals that never come do not accumulate. Returns a
+// unique ID that can be used with Cancel to remove the listener earlier.
+func OnceTimeout(sig os.Signal, fn func(), ttl time.Duration) uint32 {
+	var (
+		mu     sync.Mutex
+		fired  bool
+		expiry *time.Timer
+	)
+	id := Once(sig, func() {
+		mu.Lock()
+		fired = true
+		if expiry != nil {
+			expiry.Stop()
+		}
+		mu.Unlock()
+		fn()
+	})
+	mu.Lock()
+	if !fired && id != 0 {
+		expiry = time.AfterFunc(ttl, func() { Cancel(id) })
+	}
+	mu.Unlock()
+	return id
+}
+
+// OnReload registers fn to be called every time SIGHUP, the conventional
+// reload signal, is received. SIGHUP is removed from the shutdown signals,
+// so it no longer terminates the process. Errors returned by fn are written
+// to Logger. Returns a unique ID that can be used with Cancel to remove the
+// listener.
+func OnReload(fn func() error) uint32 {
+	lock.Lock()
+	shutdownSigs = slices.DeleteFunc(shutdownSigs, func(sig os.Signal) bool {
+		return sig == syscall.SIGHUP
+	})
+	lock.Unlock()
+
+	return On(syscall.SIGHUP, func() {
+		if err := fn(); err != nil {
+			logEvent("reload_failed", fields{"error": err}, "PID %d. Reload failed: %v.", pid, err)
+		}
+	})
+}
+
 // Cancel removes the signal listeners with the specified IDs.
 // It's safe to pass IDs that don't exist or have already been removed.
 // Zero IDs are ignored.
 func Cancel(ids ...uint32) {
+	CancelN(ids...)
+}
+
+// CancelN is like Cancel but returns the number of listeners actually
+// removed, which helps detecting double cancellations. IDs that don't exist
+// or have already been removed, as well as zero IDs, are not counted.
+func CancelN(ids ...uint32) int {
 	n := len(ids)
 	for _, id := range ids {
 		if id == 0 {
@@ -159,13 +552,77 @@ func Cancel(ids ...uint32) {
 		}
 	}
 	if n == 0 {
-		return
+		return 0
 	}
 	lock.Lock()
-	lns = slices.DeleteFunc(lns, func(l *listener) bool {
+	before := len(lns)
+	removed := remove(func(l *listener) bool {
 		return slices.Contains(ids, l.id)
 	})
+	cnt := before - len(lns)
 	lock.Unlock()
+
+	for _, l := range removed {
+		emit("cancel", l.id, l.sig)
+	}
+	return cnt
+}
+
+// Snapshot captures the registered listeners and returns a function that
+// restores exactly those, removing listeners registered since and bringing
+// back removed ones, including Once listeners that fired. It is meant for
+// tests, so listeners registered by one test case do not run in the next:
+//
+//	defer proc.Snapshot()()
+//
+// Restoring does not report cancellations to the hook set by
+// SetListenerEventHook.
+func Snapshot() (restore func()) {
+	lock.Lock()
+	saved := slices.Clone(lns)
+	lock.Unlock()
+	return func() {
+		lock.Lock()
+		defer lock.Unlock()
+		current := lns
+		lns = make([]*listener, len(saved))
+		for i, l := range saved {
+			if l.once {
+				// a fresh copy, as the original may have fired
+				l = &listener{id: l.id, fn: wrap(l.raw, true), raw: l.raw, sig: l.sig, once: true}
+			}
+			lns[i] = l
+			watch(l.sig)
+		}
+		for _, l := range current {
+			unwatch(l.sig)
+		}
+	}
+}
+
+// CancelSignal removes every listener registered for sig, e.g. to drop all
+// SIGHUP reload handlers before registering new ones, and returns the number
+// removed. Unless sig is a shutdown signal, the package then stops
+// receiving it from the OS, restoring its default behavior until a listener
+// is added again, unless other code still listens for it with signal.Notify.
+func CancelSignal(sig os.Signal) int {
+	n := signum(sig)
+	if n == -1 {
+		return 0
+	}
+	lock.Lock()
+	before := len(lns)
+	removed := remove(func(l *listener) bool {
+		return l.sig == n
+	})
+	unwatch(n)
+	cnt := before - len(lns)
+	lock.Unlock()
+
+	for _, l := range removed {
+		emit("cancel", l.id, l.sig)
+	}
+	return cnt
 }
 
 // Wait blocks until the specified signal is received.
@@ -184,49 +641,331 @@ func Wait(sig os.Signal) {
 	<-wait
 }
 
+// WaitTimeout is like Wait but gives up after d. It reports how long it
+// blocked, measured on the monotonic clock, and whether sig arrived, e.g.
+// to measure the latency of the dispatch path after signalling the
+// process. On timeout the duration is about d.
+func WaitTimeout(sig os.Signal, d time.Duration) (time.Duration, bool) {
+	start := time.Now()
+	wait := make(chan struct{})
+	id := Once(sig, func() { close(wait) })
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-wait:
+		return time.Since(start), true
+	case <-timer.C:
+		Cancel(id)
+		return time.Since(start), false
+	}
+}
+
+// WaitOrShutdown is like Wait but gives up when the process begins shutting
+// down first, returning ErrShuttingDown, right away if it already has. It
+// returns nil once sig arrives.
+// This lets goroutines waiting for, say, SIGUSR1 abandon their wait during
+// teardown instead of blocking it.
+//
+// Example:
+//
+//	for proc.WaitOrShutdown(syscall.SIGUSR1) == nil {
+//		dumpStats()
+//	}
+func WaitOrShutdown(sig os.Signal) error {
+	got := make(chan error, 2)
+	id := Once(sig, func() { got <- nil })
+	if sig == syscall.SIGTERM {
+		// the signal announcing the shutdown is the one awaited, unless it
+		// was already sent
+		if IsShuttingDown() {
+			Cancel(id)
+			return ErrShuttingDown
+		}
+		<-got
+		return nil
+	}
+	unhook := onShutdown(func() { got <- ErrShuttingDown })
+	// the hook never runs if the shutdown began before it was registered
+	err := ErrShuttingDown
+	if !IsShuttingDown() {
+		err = <-got
+	}
+	Cancel(id)
+	unhook()
+	return err
+}
+
+// NotifyContext returns a copy of parent that is cancelled when one of sigs
+// is dispatched to this package's listeners, when the returned stop function
+// is called or when parent is done, whichever happens first. Unlike
+// signal.NotifyContext it goes through the listener registry, so it
+// coexists with On and Once. context.Cause reports the signal received.
+//
+// With no sigs, the context is cancelled when the process begins shutting
+// down, with ErrShuttingDown as the cause, right away if it already has.
+// Shutdown signals such as SIGINT are dispatched to their listeners when
+// they trigger a shutdown, and SIGTERM on every shutdown, so they can be
+// listed too.
+//
+// Example:
+//
+//	ctx, stop := proc.NotifyContext(context.Background())
+//	defer stop()
+//	srv.Serve(ctx)
+func NotifyContext(parent context.Context, sigs ...os.Signal) (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	var ids []uint32
+	unhook := func() {}
+	if len(sigs) == 0 {
+		unhook = onShutdown(func() { cancel(ErrShuttingDown) })
+		// the hook never runs if the shutdown began before it was registered
+		if IsShuttingDown() {
+			cancel(ErrShuttingDown)
+		}
+	}
+	for _, sig := range sigs {
+		ids = append(ids, Once(sig, func() {
+			cancel(fmt.Errorf("%v signal received", sig))
+		}))
+	}
+
+	stopListening := context.AfterFunc(ctx, func() {
+		Cancel(ids...)
+		unhook()
+	})
+	return ctx, func() {
+		if stopListening() {
+			Cancel(ids...)
+			unhook()
+		}
+		cancel(nil)
+	}
+}
+
+// SendAfter delivers sig to the current process once d has elapsed. The
+// signal takes the same path as one received from the OS, so handlers
+// behave identically: shutdown signals trigger a graceful shutdown and exit
+// the process, other signals are dispatched to their listeners. This is
+// handy for watchdogs, e.g. terminating the process if it is not healthy
+// within 30 seconds.
+//
+// The returned function cancels the delivery if it has not happened yet.
+func SendAfter(sig os.Signal, d time.Duration) (cancel func()) {
+	t := time.AfterFunc(d, func() { handle(sig) })
+	return func() { t.Stop() }
+}
+
 // Notify dispatches a signal to all registered listeners for that signal.
 // It executes all matching listeners concurrently in separate goroutines,
 // with panic recovery. Listeners registered with Once are automatically
 // removed after execution.
 //
+// Every valid signal is also published to the channel returned by Events.
+//
 // Returns true if at least one listener was notified, false if no listeners
-// were registered for the signal or if the signal is invalid.
+// were registered for the signal, if it is suspended (see Suspend) or if
+// the signal is invalid.
 func Notify(sig os.Signal) bool {
+	notified, _ := notify(sig)
+	return notified
+}
+
+// LastNotified returns the IDs of the listeners the most recent dispatch
+// of sig ran, in the order they were started, e.g. to tell whether a
+// handler that seems not to run was registered at the time. It is empty if
+// the last dispatch found no listeners or sig has not been dispatched.
+// Dispatches held back by Suspend are not recorded until they happen on
+// resume.
+func LastNotified(sig os.Signal) []uint32 {
 	n := signum(sig)
 	if n == -1 {
-		return false
+		return nil
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return slices.Clone(lastNotified[n])
+}
+
+// notify implements Notify, additionally reporting whether the signal was
+// held back by Suspend.
+func notify(sig os.Signal) (notified, held bool) {
+	n := signum(sig)
+	if n == -1 {
+		return false, false
+	}
+
+	select {
+	case events <- sig:
+	default:
 	}
 
 	lock.Lock()
+	if suspended[n] > 0 {
+		pending[n] = true
+		lock.Unlock()
+		return false, true
+	}
 	l := len(lns)
-	fs := make([]func(), 0, l)
+	fs := make([]*listener, 0, l)
 
 	for i := l - 1; i >= 0; i-- {
 		if l := lns[i]; l.sig == n {
-			fs = append(fs, l.fn)
+			fs = append(fs, l)
 			if l.once {
 				lns = slices.Delete(lns, i, i+1)
 			}
 		}
 	}
+	ids := make([]uint32, len(fs))
+	for i, l := range fs {
+		ids[i] = l.id
+	}
+	lastNotified[n] = ids
 	lock.Unlock()
 
 	if len(fs) == 0 {
-		return false
+		return false, false
 	}
 
 	var wg sync.WaitGroup
 	var run = safeRunner(&wg)
-	for _, fn := range fs {
-		if fn != nil {
-			run(fn)
+	if limit := int(notifyConcurrency.Load()); limit > 0 && limit < len(fs) {
+		run = boundedRunner(&wg, limit)
+	}
+	for _, l := range fs {
+		emit("fire", l.id, l.sig)
+		if l.fn != nil {
+			run(l.fn)
 		}
 	}
 	wg.Wait()
 
+	return true, false
+}
+
+// Suspend stops dispatching sig to its listeners until the returned resume
+// function is called, e.g. to keep a SIGHUP reload out of a critical
+// section. Unlike Cancel, the registrations are kept. Deliveries while
+// suspended are coalesced like pending signals in the kernel: if sig was
+// dispatched at least once, resume dispatches it exactly once, returning
+// after the listeners did. Suspensions nest; dispatching resumes once
+// every resume function was called. Calling resume more than once has no
+// further effect. Trigger is not affected.
+func Suspend(sig os.Signal) (resume func()) {
+	n := signum(sig)
+	if n == -1 {
+		return func() {}
+	}
+	lock.Lock()
+	suspended[n]++
+	lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			lock.Lock()
+			suspended[n]--
+			redeliver := suspended[n] == 0 && pending[n]
+			if redeliver {
+				pending[n] = false
+			}
+			lock.Unlock()
+			if redeliver {
+				Notify(sig)
+			}
+		})
+	}
+}
+
+// Events returns a channel that receives every signal dispatched by Notify,
+// whether or not listeners are registered for it. This includes signals
+// received from the OS and the SIGTERM dispatched by Shutdown, which makes
+// it suitable as a single feed for a central event loop.
+//
+// All callers share the same channel, which buffers up to 16 signals. When
+// the buffer is full, further signals are dropped for this channel rather
+// than blocking the dispatch, so consumers should read promptly.
+func Events() <-chan os.Signal {
+	return events
+}
+
+// Trigger runs the listener with the specified ID as if its signal had been
+// received, without notifying any other listener. A listener registered
+// with Once is removed. Like Notify, it recovers from panics in the listener
+// and waits for it to complete.
+//
+// Returns false if no listener with the ID is registered.
+func Trigger(id uint32) bool {
+	if id == 0 {
+		return false
+	}
+
+	lock.Lock()
+	i := slices.IndexFunc(lns, func(l *listener) bool { return l.id == id })
+	if i == -1 {
+		lock.Unlock()
+		return false
+	}
+	l := lns[i]
+	if l.once {
+		lns = slices.Delete(lns, i, i+1)
+	}
+	lock.Unlock()
+
+	emit("fire", l.id, l.sig)
+	if l.fn != nil {
+		var wg sync.WaitGroup
+		safeRunner(&wg)(l.fn)
+		wg.Wait()
+	}
 	return true
 }
 
+// SetDefaultHandler installs fn as a catch-all for signals received from
+// the OS that are not shutdown signals and have no listener, e.g. because
+// the listeners were cancelled while the signal stayed registered with the
+// OS. Without it, such signals are only logged. Signals held back by Suspend or queued by
+// BufferEarlySignals are not passed to fn; like every dispatched signal,
+// they still reach the channel returned by Events.
+//
+// fn runs on the signal handling goroutine, so it should return quickly;
+// a panic in fn is recovered and logged. Passing nil removes it.
+func SetDefaultHandler(fn func(sig os.Signal)) {
+	if fn == nil {
+		defaultHandler.Store(nil)
+		return
+	}
+	defaultHandler.Store(&fn)
+}
+
+// SetListenerEventHook installs fn to be called on every listener lifecycle
+// event, which lets test harnesses assert exactly how listeners come and go
+// without polling. event is one of:
+//   - "add": the listener was registered by On, Once or a helper built on them
+//   - "cancel": the listener was removed by Cancel or CancelSignal
+//   - "fire": the listener is about to run, via Notify or Trigger. A Once
+//     listener is removed at that point without a separate "cancel" event.
+//
+// fn is called synchronously, outside of the package's internal lock, so it
+// may call back into the package but should return quickly. Passing nil
+// removes the hook, which is the default and costs nothing.
+func SetListenerEventHook(fn func(event string, id uint32, sig os.Signal)) {
+	if fn == nil {
+		listenerHook.Store(nil)
+		return
+	}
+	listenerHook.Store(&fn)
+}
+
+// emit reports a listener event to the hook set by SetListenerEventHook.
+func emit(event string, id uint32, n int) {
+	if hook := listenerHook.Load(); hook != nil {
+		(*hook)(event, id, syscall.Signal(n))
+	}
+}
+
 // safeRunner creates a function that executes callbacks in separate goroutines
 // with panic recovery. Each callback execution is tracked by the provided
 // WaitGroup.
@@ -241,10 +980,35 @@ func safeRunner(wg *sync.WaitGroup) func(func()) {
 	}
 }
 
+// boundedRunner is like safeRunner but runs at most limit functions at the
+// same time, blocking until one of them returns before starting another.
+func boundedRunner(wg *sync.WaitGroup, limit int) func(func()) {
+	sem := make(chan struct{}, limit)
+	run := safeRunner(wg)
+	return func(fn func()) {
+		sem <- struct{}{}
+		run(func() {
+			defer func() { <-sem }()
+			fn()
+		})
+	}
+}
+
+// Go runs fn in a new goroutine that recovers from a panic in fn instead of
+// crashing the process. Like a panicking listener, the panic value and the
+// stack trace are written to Logger.
+func Go(fn func()) {
+	go func() {
+		defer recovery()
+		fn()
+	}()
+}
+
 // recovery handles panics that occur during signal listener execution.
 // It logs the panic value and stack trace for debugging purposes.
 func recovery() {
 	if p := recover(); p != nil {
-		debugf("%+v\n%s", p, debug.Stack())
+		stack := debug.Stack()
+		logEvent("panic_recovered", fields{"panic": fmt.Sprint(p), "stack": string(stack)}, "%+v\n%s", p, stack)
 	}
 }
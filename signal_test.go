@@ -1,8 +1,17 @@
 package proc
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 )
 
 // A custom signal type that is NOT syscall.Signal to force signum() -> -1
@@ -31,6 +40,27 @@ func TestSafeRunner_RecoveryDoesNotPanic(t *testing.T) {
 	// If recovery failed, test would panic; reaching here is success.
 }
 
+func TestGo_RecoversAndLogs(t *testing.T) {
+	var buf syncBuffer
+	old := Logger
+	Logger = &buf
+	defer func() { Logger = old }()
+
+	done := make(chan struct{})
+	Go(func() {
+		defer close(done)
+		panic("boom")
+	})
+	<-done
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(buf.String(), "boom") {
+		if time.Now().After(deadline) {
+			t.Fatalf("panic was not logged, got %q", buf.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
 func TestSignal_Cancel_ZeroIDs(t *testing.T) {
 	// Test that cancelling with zero IDs is safe
 	Cancel(0, 0, 0)
@@ -43,6 +73,23 @@ func TestSignal_Cancel_InvalidIDs(t *testing.T) {
 	// Should not panic
 }
 
+func TestSignal_CancelN_CountsRemoved(t *testing.T) {
+	live1 := On(syscall.SIGINT, func() {})
+	live2 := Once(syscall.SIGTERM, func() {})
+	dead := On(syscall.SIGINT, func() {})
+	Cancel(dead)
+
+	if n := CancelN(live1, dead, 0, live2, 99999, live1); n != 2 {
+		t.Fatalf("CancelN = %d, want 2", n)
+	}
+	if n := CancelN(live1, live2); n != 0 {
+		t.Fatalf("CancelN of already removed IDs = %d, want 0", n)
+	}
+	if n := CancelN(); n != 0 {
+		t.Fatalf("CancelN() = %d, want 0", n)
+	}
+}
+
 func TestSignal_InvalidSignalReturnsZeroID(t *testing.T) {
 	// When adding an invalid signal, should return 0
 	// This happens when signum returns -1
@@ -52,3 +99,688 @@ func TestSignal_InvalidSignalReturnsZeroID(t *testing.T) {
 		t.Fatalf("Invalid signal should return ID 0, got %d", id)
 	}
 }
+
+func TestSignal_DisableKeepsListeners(t *testing.T) {
+	Disable()
+	defer Enable()
+
+	if sigstop != nil || mask != 0 {
+		t.Fatalf("Disable should stop handling and clear the mask")
+	}
+	Disable() // no-op when already disabled
+
+	var called bool
+	id := On(syscall.SIGTERM, func() { called = true })
+	defer Cancel(id)
+	if mask != 0 {
+		t.Fatalf("On must not register with the OS while disabled")
+	}
+	if !Notify(syscall.SIGTERM) || !called {
+		t.Fatal("Notify should still dispatch while disabled")
+	}
+}
+
+func TestEvents_ReceivesDispatchedSignals(t *testing.T) {
+	drainEvents()
+
+	Notify(syscall.SIGTERM)
+
+	select {
+	case sig := <-Events():
+		if sig != syscall.SIGTERM {
+			t.Fatalf("Events delivered %v, want SIGTERM", sig)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Events did not deliver the dispatched signal")
+	}
+}
+
+func TestEvents_DropsWhenFull(t *testing.T) {
+	drainEvents()
+	defer drainEvents()
+
+	done := make(chan struct{})
+	go func() {
+		for range eventsBuffer + 5 {
+			Notify(syscall.SIGTERM)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on a full Events channel")
+	}
+	if n := len(Events()); n != eventsBuffer {
+		t.Fatalf("Events buffered %d signals, want %d", n, eventsBuffer)
+	}
+}
+
+func drainEvents() {
+	for {
+		select {
+		case <-Events():
+		default:
+			return
+		}
+	}
+}
+
+func TestOnReload_RunsAndLogsError(t *testing.T) {
+	oldSigs, oldLogger := slices.Clone(shutdownSigs), Logger
+	defer func() { SetShutdownSignals(oldSigs...); Logger = oldLogger }()
+
+	var buf syncBuffer
+	Logger = &buf
+
+	var ran bool
+	id := OnReload(func() error {
+		ran = true
+		return errors.New("bad config")
+	})
+	defer Cancel(id)
+
+	if slices.Contains(shutdownSigs, os.Signal(syscall.SIGHUP)) {
+		t.Fatal("OnReload should remove SIGHUP from the shutdown signals")
+	}
+	if !Notify(syscall.SIGHUP) {
+		t.Fatal("Notify(SIGHUP) should reach the reload listener")
+	}
+	if !ran {
+		t.Fatal("reload callback did not run")
+	}
+	if !strings.Contains(buf.String(), "bad config") {
+		t.Fatalf("reload error should be logged, got: %q", buf.String())
+	}
+}
+
+func TestSetShutdownSignals(t *testing.T) {
+	oldSigs := slices.Clone(shutdownSigs)
+	defer SetShutdownSignals(oldSigs...)
+
+	SetShutdownSignals(syscall.SIGTERM)
+	if !slices.Equal(shutdownSigs, []os.Signal{syscall.SIGTERM}) {
+		t.Fatalf("shutdown signals = %v, want [SIGTERM]", shutdownSigs)
+	}
+}
+
+func TestShutdownSignals(t *testing.T) {
+	oldSigs := slices.Clone(shutdownSigs)
+	defer SetShutdownSignals(oldSigs...)
+
+	SetShutdownSignals(syscall.SIGTERM, syscall.SIGHUP)
+	defer Cancel(OnReload(func() error { return nil }))
+	got := ShutdownSignals()
+	if !slices.Equal(got, []os.Signal{syscall.SIGTERM}) {
+		t.Fatalf("ShutdownSignals() = %v, want [SIGTERM] after OnReload", got)
+	}
+	got[0] = syscall.SIGINT
+	if shutdownSigs[0] != syscall.SIGTERM {
+		t.Fatal("modifying the result changed the shutdown signals")
+	}
+}
+
+func TestWaitOrShutdown(t *testing.T) {
+	shuttingDown.Store(false)
+	shutdownDone = make(chan struct{})
+	lock.Lock()
+	before := len(lns)
+	lock.Unlock()
+
+	result := make(chan error, 1)
+	go func() { result <- WaitOrShutdown(syscall.SIGINT) }()
+	time.Sleep(10 * time.Millisecond)
+	Notify(syscall.SIGINT)
+	if err := <-result; err != nil {
+		t.Fatalf("WaitOrShutdown after SIGINT = %v, want nil", err)
+	}
+
+	go func() { result <- WaitOrShutdown(syscall.SIGINT) }()
+	time.Sleep(10 * time.Millisecond)
+	notifyShutdown(syscall.SIGTERM)
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrShuttingDown) {
+			t.Fatalf("WaitOrShutdown after shutdown = %v, want ErrShuttingDown", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitOrShutdown did not return on shutdown")
+	}
+
+	// the pending SIGINT listener must have been removed
+	lock.Lock()
+	after := len(lns)
+	lock.Unlock()
+	if after != before {
+		t.Fatalf("%d listeners registered after WaitOrShutdown, want %d", after, before)
+	}
+}
+
+func TestWaitOrShutdown_AfterShutdownBegan(t *testing.T) {
+	beginShutdown()
+	for _, sig := range []os.Signal{syscall.SIGINT, syscall.SIGTERM} {
+		result := make(chan error, 1)
+		go func() { result <- WaitOrShutdown(sig) }()
+		select {
+		case err := <-result:
+			if !errors.Is(err, ErrShuttingDown) {
+				t.Fatalf("WaitOrShutdown(%v) = %v, want ErrShuttingDown", sig, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("WaitOrShutdown(%v) blocked after the shutdown began", sig)
+		}
+	}
+}
+
+func TestNotifyContext_AfterShutdownBegan(t *testing.T) {
+	beginShutdown()
+	ctx, stop := NotifyContext(context.Background())
+	defer stop()
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("context not cancelled although the shutdown already began")
+	}
+	if !errors.Is(context.Cause(ctx), ErrShuttingDown) {
+		t.Fatalf("cause = %v, want ErrShuttingDown", context.Cause(ctx))
+	}
+}
+
+func TestTrigger_RunsOnlyTargetListener(t *testing.T) {
+	var target, other int
+	id := On(syscall.SIGTERM, func() { target++ })
+	otherID := On(syscall.SIGTERM, func() { other++ })
+	defer Cancel(id, otherID)
+
+	if !Trigger(id) || !Trigger(id) {
+		t.Fatal("Trigger should find a registered listener")
+	}
+	if target != 2 || other != 0 {
+		t.Fatalf("after Trigger: target=%d other=%d, want 2 and 0", target, other)
+	}
+}
+
+func TestTrigger_OnceIsRemoved(t *testing.T) {
+	var cnt int
+	id := Once(syscall.SIGTERM, func() { cnt++ })
+
+	if !Trigger(id) {
+		t.Fatal("first Trigger should find the Once listener")
+	}
+	if Trigger(id) {
+		t.Fatal("Once listener should be removed after Trigger")
+	}
+	if cnt != 1 {
+		t.Fatalf("Once listener ran %d times, want 1", cnt)
+	}
+}
+
+func TestTrigger_UnknownID(t *testing.T) {
+	if Trigger(0) || Trigger(99999) {
+		t.Fatal("Trigger should return false for unknown IDs")
+	}
+}
+
+func TestCancelSignal_KeepsShutdownSignalWatched(t *testing.T) {
+	On(syscall.SIGINT, func() {})
+	if n := CancelSignal(syscall.SIGINT); n != 1 {
+		t.Fatalf("CancelSignal(SIGINT) = %d, want 1", n)
+	}
+	lock.Lock()
+	watched := sigstop == nil || mask&(1<<uint(syscall.SIGINT)) != 0
+	lock.Unlock()
+	if !watched {
+		t.Fatal("shutdown signal SIGINT should stay registered with the OS")
+	}
+	if CancelSignal(bogusSignal{}) != 0 {
+		t.Fatal("CancelSignal of an invalid signal should remove nothing")
+	}
+}
+
+func TestSetListenerEventHook(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	SetListenerEventHook(func(event string, id uint32, sig os.Signal) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, event+" "+sig.String())
+	})
+	defer SetListenerEventHook(nil)
+
+	on := On(syscall.SIGINT, func() {})
+	Once(syscall.SIGINT, func() {})
+	Notify(syscall.SIGINT)
+	Trigger(on)
+	Cancel(on)
+	On(syscall.SIGINT, func() {})
+	CancelSignal(syscall.SIGINT)
+
+	SetListenerEventHook(nil)
+	On(syscall.SIGINT, func() {})
+	CancelSignal(syscall.SIGINT)
+
+	mu.Lock()
+	defer mu.Unlock()
+	intr := syscall.SIGINT.String()
+	want := []string{
+		"add " + intr, "add " + intr,
+		"fire " + intr, "fire " + intr,
+		"fire " + intr,
+		"cancel " + intr,
+		"add " + intr, "cancel " + intr,
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+}
+
+func TestNotifyContext(t *testing.T) {
+	shuttingDown.Store(false)
+	shutdownDone = make(chan struct{})
+	lock.Lock()
+	before := len(lns)
+	lock.Unlock()
+
+	ctx, stop := NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	Notify(syscall.SIGTERM)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context was not cancelled by SIGTERM")
+	}
+	if cause := context.Cause(ctx); cause == nil || !strings.Contains(cause.Error(), syscall.SIGTERM.String()) {
+		t.Fatalf("cause = %v, want it to name the signal", cause)
+	}
+
+	// shutdown without explicit signals
+	ctx, stop = NotifyContext(context.Background())
+	notifyShutdown(syscall.SIGTERM)
+	<-ctx.Done()
+	if !errors.Is(context.Cause(ctx), ErrShuttingDown) {
+		t.Fatalf("cause = %v, want ErrShuttingDown", context.Cause(ctx))
+	}
+	stop()
+
+	// stop releases the listeners without cancellation by a signal
+	ctx, stop = NotifyContext(context.Background(), syscall.SIGINT)
+	stop()
+	if !errors.Is(context.Cause(ctx), context.Canceled) {
+		t.Fatalf("cause after stop = %v, want context.Canceled", context.Cause(ctx))
+	}
+
+	time.Sleep(10 * time.Millisecond) // let AfterFunc cleanups finish
+	lock.Lock()
+	after := len(lns)
+	lock.Unlock()
+	if after != before {
+		t.Fatalf("%d listeners registered after NotifyContext, want %d", after, before)
+	}
+}
+
+func TestBufferEarlySignals(t *testing.T) {
+	defer func() {
+		lock.Lock()
+		early, earlyWatched, earlyQueue, earlyDone = false, nil, nil, [numSig]bool{}
+		lock.Unlock()
+	}()
+	BufferEarlySignals(syscall.SIGALRM)
+
+	for range earlyBufferSize + 4 {
+		handle(syscall.SIGALRM)
+	}
+
+	got := make(chan struct{}, 2*earlyBufferSize)
+	id := On(syscall.SIGALRM, func() { got <- struct{}{} })
+	defer Cancel(id)
+
+	for i := range earlyBufferSize {
+		select {
+		case <-got:
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of %d queued signals were replayed", i, earlyBufferSize)
+		}
+	}
+	select {
+	case <-got:
+		t.Fatalf("more than %d signals were replayed", earlyBufferSize)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// signals arriving once a listener exists are dispatched as usual
+	handle(syscall.SIGALRM)
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("signal after registration was not dispatched")
+	}
+}
+
+func TestSuspend(t *testing.T) {
+	var calls atomic.Int32
+	id := On(syscall.SIGINT, func() { calls.Add(1) })
+	defer Cancel(id)
+
+	resume := Suspend(syscall.SIGINT)
+	inner := Suspend(syscall.SIGINT)
+	for range 3 {
+		if Notify(syscall.SIGINT) {
+			t.Fatal("Notify should not dispatch a suspended signal")
+		}
+	}
+	if !Trigger(id) {
+		t.Fatal("Trigger should bypass the suspension")
+	}
+	inner()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("listener ran %d times while suspended, want only the Trigger", n)
+	}
+
+	resume()
+	resume()
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("listener ran %d times after resume, want 2 (Trigger plus one coalesced dispatch)", n)
+	}
+	if !Notify(syscall.SIGINT) || calls.Load() != 3 {
+		t.Fatal("Notify should dispatch again after resume")
+	}
+}
+
+func TestSetMaxListeners(t *testing.T) {
+	var buf syncBuffer
+	old := Logger
+	Logger = &buf
+	defer func() { Logger = old }()
+
+	lock.Lock()
+	n := len(lns)
+	lock.Unlock()
+	SetMaxListeners(n + 2)
+	defer SetMaxListeners(0)
+
+	var ids []uint32
+	defer func() { Cancel(ids...) }()
+	for range 2 {
+		ids = append(ids, Once(syscall.SIGINT, func() {}))
+	}
+	if strings.Contains(buf.String(), "possible leak") {
+		t.Fatalf("warned at the limit: %q", buf.String())
+	}
+	for range 3 {
+		ids = append(ids, Once(syscall.SIGINT, func() {}))
+	}
+	if got := strings.Count(buf.String(), "possible leak"); got != 1 {
+		t.Fatalf("warned %d times past the limit, want once: %q", got, buf.String())
+	}
+}
+
+func TestShutdownSignalsAreCoalesced(t *testing.T) {
+	oldKill, oldExit, oldLogger := killFn, exitFn, Logger
+	defer func() { killFn, exitFn, Logger = oldKill, oldExit, oldLogger }()
+	var buf syncBuffer
+	Logger = &buf
+	SetTimeToForceQuit(0)
+
+	release := make(chan struct{})
+	var runs, exits atomic.Int32
+	killFn = func(syscall.Signal) error { return nil }
+	id := On(syscall.SIGTERM, func() {
+		runs.Add(1)
+		<-release
+	})
+	defer Cancel(id)
+	exited := make(chan struct{})
+	exitFn = func(int) {
+		exits.Add(1)
+		close(exited)
+	}
+
+	handle(syscall.SIGTERM)
+	deadline := time.Now().Add(time.Second)
+	for runs.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("shutdown did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	handle(syscall.SIGINT)
+	InitiateShutdown()
+	for strings.Count(buf.String(), "Already shutting down") != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("want 2 ignored shutdown requests logged, got %q", buf.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-exited
+	for shutdownRunning.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if r, e := runs.Load(), exits.Load(); r != 1 || e != 1 {
+		t.Fatalf("listener runs = %d, exits = %d, want a single shutdown", r, e)
+	}
+}
+
+func TestSetDefaultHandler(t *testing.T) {
+	got := make(chan os.Signal, 4)
+	SetDefaultHandler(func(sig os.Signal) { got <- sig })
+	defer SetDefaultHandler(nil)
+
+	handle(syscall.SIGALRM)
+	if len(got) != 1 || <-got != syscall.SIGALRM {
+		t.Fatal("default handler should get a signal without listeners")
+	}
+
+	id := On(syscall.SIGALRM, func() {})
+	handle(syscall.SIGALRM)
+	resume := Suspend(syscall.SIGALRM)
+	handle(syscall.SIGALRM)
+	resume()
+	Cancel(id)
+	if len(got) != 0 {
+		t.Fatalf("default handler got %d signals that had a listener", len(got))
+	}
+
+	SetDefaultHandler(func(os.Signal) { panic("boom") })
+	old := Logger
+	Logger = nil
+	defer func() { Logger = old }()
+	handle(syscall.SIGALRM) // must not crash
+}
+
+func TestSignalNumber(t *testing.T) {
+	for _, tc := range []struct {
+		sig  os.Signal
+		want int
+		ok   bool
+	}{
+		{syscall.SIGINT, 2, true},
+		{syscall.SIGTERM, 15, true},
+		{syscall.Signal(numSig), -1, false},
+		{syscall.Signal(-1), -1, false},
+		{bogusSignal{}, -1, false},
+	} {
+		if n, ok := SignalNumber(tc.sig); n != tc.want || ok != tc.ok {
+			t.Errorf("SignalNumber(%v) = %d, %v; want %d, %v", tc.sig, n, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	lock.Lock()
+	before := len(lns)
+	lock.Unlock()
+
+	elapsed, ok := WaitTimeout(syscall.SIGALRM, 20*time.Millisecond)
+	if ok || elapsed < 20*time.Millisecond {
+		t.Fatalf("WaitTimeout without a signal = %v, %v; want at least 20ms, false", elapsed, ok)
+	}
+	lock.Lock()
+	after := len(lns)
+	lock.Unlock()
+	if after != before {
+		t.Fatalf("%d listeners registered after a timed out wait, want %d", after, before)
+	}
+
+	time.AfterFunc(10*time.Millisecond, func() { Notify(syscall.SIGALRM) })
+	elapsed, ok = WaitTimeout(syscall.SIGALRM, 5*time.Second)
+	if !ok || elapsed < 10*time.Millisecond || elapsed > 5*time.Second {
+		t.Fatalf("WaitTimeout with a signal = %v, %v; want about 10ms, true", elapsed, ok)
+	}
+}
+
+func TestLastNotified(t *testing.T) {
+	sig := syscall.SIGALRM
+	a := On(sig, func() {})
+	b := Once(sig, func() {})
+	defer Cancel(a)
+	Notify(sig)
+	if got := LastNotified(sig); !slices.Equal(got, []uint32{b, a}) {
+		t.Fatalf("LastNotified = %v, want [%d %d]", got, b, a)
+	}
+
+	Cancel(a)
+	Notify(sig)
+	if got := LastNotified(sig); len(got) != 0 {
+		t.Fatalf("LastNotified after a dispatch without listeners = %v, want none", got)
+	}
+}
+
+func TestOnceTimeout(t *testing.T) {
+	sig := syscall.SIGALRM
+
+	var fired atomic.Int32
+	id := OnceTimeout(sig, func() { fired.Add(1) }, 20*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	if CancelN(id) != 0 {
+		t.Fatal("listener still registered after its TTL")
+	}
+	Notify(sig)
+	if fired.Load() != 0 {
+		t.Fatal("listener fired after its TTL")
+	}
+
+	id = OnceTimeout(sig, func() { fired.Add(1) }, time.Second)
+	Notify(sig)
+	Notify(sig)
+	if fired.Load() != 1 {
+		t.Fatalf("listener fired %d times within its TTL, want once", fired.Load())
+	}
+	if CancelN(id) != 0 {
+		t.Fatal("listener still registered after firing")
+	}
+}
+
+// TestSignalHandlingNeverStarted runs TestSignalHandlingNeverStarted_Helper
+// in a test binary started with PROC_NOSIGNAL, so signal handling is never
+// started.
+func TestSignalHandlingNeverStarted(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSignalHandlingNeverStarted_Helper$", "-test.v")
+	cmd.Env = append(os.Environ(), "PROC_NOSIGNAL=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "--- PASS: TestSignalHandlingNeverStarted_Helper") {
+		t.Fatalf("helper failed: %v\n%s", err, out)
+	}
+}
+
+func TestSignalHandlingNeverStarted_Helper(t *testing.T) {
+	if os.Getenv("PROC_NOSIGNAL") == "" {
+		t.Skip("only run by TestSignalHandlingNeverStarted")
+	}
+	if sigstop != nil {
+		t.Fatal("signal handling should not be started")
+	}
+	oldKill, oldExit := killFn, exitFn
+	defer func() { killFn, exitFn = oldKill, oldExit }()
+	killFn = func(syscall.Signal) error { return nil }
+	exited := false
+	exitFn = func(int) { exited = true }
+
+	sig := syscall.SIGALRM
+	var calls atomic.Int32
+	id := On(sig, func() { calls.Add(1) })
+	Once(sig, func() { calls.Add(1) })
+	OnceTimeout(sig, func() { calls.Add(1) }, time.Minute)
+	if !Notify(sig) || calls.Load() != 3 {
+		t.Fatalf("Notify ran %d listeners, want 3", calls.Load())
+	}
+	if !Trigger(id) {
+		t.Fatal("Trigger should run a registered listener")
+	}
+	resume := Suspend(sig)
+	Notify(sig)
+	resume()
+	if calls.Load() != 5 {
+		t.Fatalf("listeners ran %d times, want 5", calls.Load())
+	}
+	if _, ok := WaitTimeout(sig, time.Millisecond); ok {
+		t.Fatal("WaitTimeout should time out")
+	}
+	if CancelSignal(sig) != 1 {
+		t.Fatal("CancelSignal should remove the remaining listener")
+	}
+
+	SetShutdownSignals(syscall.SIGTERM)
+	defer Cancel(OnReload(func() error { return nil }))
+	if _, _, ok := LastSignal(); ok {
+		t.Fatal("no signal should have been received")
+	}
+
+	Once(syscall.SIGTERM, func() { calls.Add(1) })
+	if err := ShutdownNow(ShutdownOptions{}); err != nil {
+		t.Fatalf("ShutdownNow failed: %v", err)
+	}
+	InitiateShutdown()
+	if calls.Load() != 6 || !exited {
+		t.Fatalf("shutdown ran %d listeners and exited %v, want 6 and true", calls.Load(), exited)
+	}
+}
+
+func TestSetNotifyConcurrency(t *testing.T) {
+	SetNotifyConcurrency(2)
+	defer SetNotifyConcurrency(0)
+
+	sig := syscall.SIGALRM
+	var running, peak, ran atomic.Int32
+	var ids []uint32
+	for range 10 {
+		ids = append(ids, On(sig, func() {
+			n := running.Add(1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			ran.Add(1)
+		}))
+	}
+	defer Cancel(ids...)
+
+	Notify(sig)
+	if ran.Load() != 10 {
+		t.Fatalf("Notify returned after %d of 10 listeners", ran.Load())
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("%d listeners ran at the same time, want at most 2", peak.Load())
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	sig := syscall.SIGALRM
+	var kept, fired, added atomic.Int32
+	keep := On(sig, func() { kept.Add(1) })
+	defer Cancel(keep)
+	Once(sig, func() { fired.Add(1) })
+
+	restore := Snapshot()
+	Notify(sig)
+	On(sig, func() { added.Add(1) })
+	restore()
+
+	Notify(sig)
+	if kept.Load() != 2 || fired.Load() != 2 || added.Load() != 0 {
+		t.Fatalf("after restore: kept ran %d, fired Once ran %d, added ran %d; want 2, 2, 0",
+			kept.Load(), fired.Load(), added.Load())
+	}
+}
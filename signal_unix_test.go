@@ -3,6 +3,9 @@
 package proc
 
 import (
+	"os"
+	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"testing"
@@ -157,3 +160,211 @@ func TestWait_MultipleWaiters(t *testing.T) {
 		t.Fatal("Not all waiters were unblocked within timeout")
 	}
 }
+
+func TestSignal_EnableAfterDisable(t *testing.T) {
+	got := make(chan struct{}, 1)
+	id := On(syscall.SIGUSR1, func() { got <- struct{}{} })
+	defer Cancel(id)
+
+	Disable()
+	Enable()
+	Enable() // no-op when already enabled
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("listener should receive OS signals after re-enabling")
+	}
+}
+
+func TestSignal_StopStartSignalHandling(t *testing.T) {
+	got := make(chan struct{}, 1)
+	id := On(syscall.SIGUSR2, func() { got <- struct{}{} })
+	defer Cancel(id)
+
+	for range 3 {
+		StopSignalHandling()
+		if sigstop != nil || sigdone != nil {
+			t.Fatal("StopSignalHandling should stop the handling goroutine")
+		}
+		StartSignalHandling()
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("listener should receive OS signals after restarting")
+	}
+}
+
+func TestStartSignalHandling_NoShutdownSignals(t *testing.T) {
+	oldSigs, oldLogger := ShutdownSignals(), Logger
+	defer func() {
+		StopSignalHandling()
+		SetShutdownSignals(oldSigs...)
+		StartSignalHandling()
+		Logger = oldLogger
+	}()
+	var buf syncBuffer
+	Logger = &buf
+
+	SetShutdownSignals()
+	StopSignalHandling()
+	StartSignalHandling()
+
+	// SIGWINCH is ignored by default, so it is harmless if not relayed
+	syscall.Kill(syscall.Getpid(), syscall.SIGWINCH)
+	time.Sleep(50 * time.Millisecond)
+	if strings.Contains(buf.String(), "Received") {
+		t.Fatalf("signal without listener relayed to the package: %q", buf.String())
+	}
+}
+
+func TestSendAfter_DeliversSignal(t *testing.T) {
+	got := make(chan time.Time, 1)
+	id := Once(syscall.SIGUSR1, func() { got <- time.Now() })
+	defer Cancel(id)
+
+	start := time.Now()
+	SendAfter(syscall.SIGUSR1, 30*time.Millisecond)
+
+	select {
+	case at := <-got:
+		if at.Sub(start) < 30*time.Millisecond {
+			t.Fatalf("signal delivered after %v, want >= 30ms", at.Sub(start))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendAfter did not deliver the signal")
+	}
+}
+
+func TestSendAfter_Cancel(t *testing.T) {
+	got := make(chan struct{}, 1)
+	id := Once(syscall.SIGUSR1, func() { got <- struct{}{} })
+	defer Cancel(id)
+
+	cancel := SendAfter(syscall.SIGUSR1, 30*time.Millisecond)
+	cancel()
+
+	select {
+	case <-got:
+		t.Fatal("cancelled SendAfter should not deliver the signal")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestLastSignal(t *testing.T) {
+	got := make(chan struct{}, 1)
+	id := Once(syscall.SIGUSR2, func() { got <- struct{}{} })
+	defer Cancel(id)
+
+	start := time.Now()
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("signal was not delivered")
+	}
+
+	sig, at, ok := LastSignal()
+	if !ok || sig != syscall.SIGUSR2 {
+		t.Fatalf("LastSignal() = %v, %v, want SIGUSR2, true", sig, ok)
+	}
+	if at.Before(start) {
+		t.Fatalf("LastSignal time %v is before the signal was sent (%v)", at, start)
+	}
+}
+
+func TestCancelSignal(t *testing.T) {
+	On(syscall.SIGUSR1, func() {})
+	Once(syscall.SIGUSR1, func() {})
+	keep := On(syscall.SIGUSR2, func() {})
+	defer Cancel(keep)
+
+	if n := CancelSignal(syscall.SIGUSR1); n != 2 {
+		t.Fatalf("CancelSignal(SIGUSR1) = %d, want 2", n)
+	}
+	if Notify(syscall.SIGUSR1) {
+		t.Fatal("no SIGUSR1 listener should be left")
+	}
+	if !Notify(syscall.SIGUSR2) {
+		t.Fatal("SIGUSR2 listener should be kept")
+	}
+
+	lock.Lock()
+	watched := mask&(1<<uint(syscall.SIGUSR1)) != 0
+	lock.Unlock()
+	if watched {
+		t.Fatal("SIGUSR1 should be deregistered from the OS")
+	}
+
+	if n := CancelSignal(syscall.SIGUSR1); n != 0 {
+		t.Fatalf("second CancelSignal(SIGUSR1) = %d, want 0", n)
+	}
+}
+
+func TestCancelSignal_KeepsOtherHandlers(t *testing.T) {
+	// another package listening for the signal keeps receiving it
+	user := make(chan os.Signal, 1)
+	signal.Notify(user, syscall.SIGUSR2)
+	defer signal.Stop(user)
+
+	alrm := make(chan struct{}, 1)
+	alrmID := On(syscall.SIGALRM, func() {
+		select {
+		case alrm <- struct{}{}:
+		default:
+		}
+	})
+	defer Cancel(alrmID)
+
+	On(syscall.SIGUSR2, func() {})
+	if n := CancelSignal(syscall.SIGUSR2); n != 1 {
+		t.Fatalf("CancelSignal removed %d listeners, want 1", n)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-user:
+	case <-time.After(time.Second):
+		t.Fatal("signal.Notify channel of another package lost SIGUSR2")
+	}
+
+	// signals still listened for stay registered
+	if err := syscall.Kill(os.Getpid(), syscall.SIGALRM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-alrm:
+	case <-time.After(time.Second):
+		t.Fatal("SIGALRM listener not called after CancelSignal")
+	}
+}
+
+func TestUnwatch_ReregistersCancelledSignals(t *testing.T) {
+	// SIGALRM stays registered after its only listener is cancelled, and is
+	// dropped when another signal is deregistered; a new listener must
+	// register it again
+	id := On(syscall.SIGALRM, func() {})
+	Cancel(id)
+	On(syscall.SIGUSR2, func() {})
+	CancelSignal(syscall.SIGUSR2)
+
+	got := make(chan struct{}, 1)
+	id = Once(syscall.SIGALRM, func() { got <- struct{}{} })
+	defer Cancel(id)
+	if err := SignalSelf(syscall.SIGALRM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("listener added after the signal was dropped was not called")
+	}
+}
@@ -0,0 +1,20 @@
+package proc
+
+import (
+	"os"
+	"sync"
+)
+
+// stdoutIsTTY caches whether os.Stdout is a terminal.
+var stdoutIsTTY = sync.OnceValue(func() bool {
+	return isTerminal(os.Stdout.Fd())
+})
+
+// IsTTY reports whether the standard output of the current process is a
+// terminal. It returns false when stdout is redirected to a file, a pipe or
+// a device such as /dev/null, which makes it suitable for deciding whether
+// to emit colored or interactive output. The result is determined on first
+// use and cached.
+func IsTTY() bool {
+	return stdoutIsTTY()
+}
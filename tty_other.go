@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+package proc
+
+// isTerminal always reports false on platforms without terminal support.
+func isTerminal(_ uintptr) bool {
+	return false
+}
@@ -0,0 +1,50 @@
+package proc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsTerminal_File(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	defer f.Close()
+
+	if isTerminal(f.Fd()) {
+		t.Fatal("a regular file should not be reported as a terminal")
+	}
+}
+
+func TestIsTerminal_Pipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe error: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if isTerminal(w.Fd()) {
+		t.Fatal("a pipe should not be reported as a terminal")
+	}
+}
+
+func TestIsTerminal_DevNull(t *testing.T) {
+	f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Open %s error: %v", os.DevNull, err)
+	}
+	defer f.Close()
+
+	if isTerminal(f.Fd()) {
+		t.Fatalf("%s should not be reported as a terminal", os.DevNull)
+	}
+}
+
+func TestIsTTY_Cached(t *testing.T) {
+	if IsTTY() != IsTTY() {
+		t.Fatal("IsTTY should return a stable cached value")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+package proc
+
+import "syscall"
+
+// isTerminal reports whether fd refers to a console. Files, pipes and the
+// NUL device have no console mode, so GetConsoleMode fails for them.
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &mode) == nil
+}
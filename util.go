@@ -1,9 +1,12 @@
 package proc
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
+	"time"
 )
 
 // Logger is the output destination for debug messages.
@@ -11,14 +14,81 @@ import (
 // Set to io.Discard to disable debug logging.
 var Logger io.Writer
 
+// IsDebug reports whether debug messages are written, i.e. whether Logger
+// is neither nil nor io.Discard. Hot paths check it before building
+// expensive log arguments, which are otherwise evaluated even when nothing
+// is logged.
+func IsDebug() bool {
+	return Logger != nil && Logger != io.Discard
+}
+
+// jsonLogging reports whether diagnostics are written as JSON, see
+// SetJSONLogging.
+var jsonLogging atomic.Bool
+
+// SetJSONLogging switches the diagnostics written to Logger and
+// ExecOptions.Logger between text lines, the default, and JSON objects, one
+// per line, for log aggregation pipelines. Each object holds "ts", "pid",
+// "event", a stable name such as "signal_received" or "shutdown_started",
+// "msg", the text line, and fields specific to the event, e.g. "signal".
+func SetJSONLogging(on bool) {
+	jsonLogging.Store(on)
+}
+
+// fields holds the structured fields of a diagnostic event.
+type fields map[string]any
+
 // debugf outputs a formatted debug message to the Logger.
 // If Logger is nil or io.Discard, no output is produced.
 // The format string follows fmt.Printf conventions.
 func debugf(format string, args ...any) {
-	if Logger != nil && Logger != io.Discard {
-		_, err := fmt.Fprintf(Logger, format+"\n", args...)
-		if err != nil {
-			_, _ = fmt.Fprint(os.Stderr, err)
+	logEvent("debug", nil, format, args...)
+}
+
+// logEvent writes the diagnostic event named event to the Logger, as the
+// message formatted from format and args, or as a JSON object also holding
+// f if enabled by SetJSONLogging. If Logger is nil or io.Discard, no output
+// is produced.
+func logEvent(event string, f fields, format string, args ...any) {
+	if IsDebug() {
+		writeEvent(Logger, event, f, format, args...)
+	}
+}
+
+// writeEvent writes a diagnostic event to w, see logEvent. Write errors
+// are reported to os.Stderr.
+func writeEvent(w io.Writer, event string, f fields, format string, args ...any) {
+	var err error
+	if jsonLogging.Load() {
+		obj := make(map[string]any, len(f)+4)
+		for k, v := range f {
+			obj[k] = jsonValue(v)
+		}
+		obj["ts"] = time.Now().Format(time.RFC3339Nano)
+		obj["pid"] = pid
+		obj["event"] = event
+		obj["msg"] = fmt.Sprintf(format, args...)
+		var b []byte
+		if b, err = json.Marshal(obj); err == nil {
+			_, err = w.Write(append(b, '\n'))
 		}
+	} else {
+		_, err = fmt.Fprintf(w, format+"\n", args...)
+	}
+	if err != nil {
+		_, _ = fmt.Fprint(os.Stderr, err)
+	}
+}
+
+// jsonValue returns the representation of v in a JSON event: errors and
+// fmt.Stringers, such as signals and durations, as their text.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
 	}
 }
@@ -2,11 +2,35 @@ package proc
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
+// syncBuffer is a bytes.Buffer safe for concurrent use, for capturing Logger
+// output written from other goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
 func TestDebugf_WithNilLogger(t *testing.T) {
 	// Test that debugf doesn't panic with nil Logger
 	old := Logger
@@ -27,6 +51,42 @@ func TestDebugf_WithDiscard(t *testing.T) {
 	debugf("test message")
 }
 
+func TestIsDebug(t *testing.T) {
+	old := Logger
+	defer func() { Logger = old }()
+
+	for _, tc := range []struct {
+		logger io.Writer
+		want   bool
+	}{
+		{nil, false},
+		{io.Discard, false},
+		{&bytes.Buffer{}, true},
+	} {
+		Logger = tc.logger
+		if got := IsDebug(); got != tc.want {
+			t.Fatalf("IsDebug() with Logger %T = %v, want %v", tc.logger, got, tc.want)
+		}
+	}
+}
+
+func TestDebugf_GuardedDoesNotAllocate(t *testing.T) {
+	old := Logger
+	Logger = io.Discard
+	defer func() { Logger = old }()
+
+	i := 1000
+	allocs := testing.AllocsPerRun(100, func() {
+		i++
+		if IsDebug() {
+			debugf("test message %d", i)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("guarded debugf allocated %v times per call, want 0", allocs)
+	}
+}
+
 func TestDebugf_FormatsCorrectly(t *testing.T) {
 	var buf bytes.Buffer
 	old := Logger
@@ -84,3 +144,80 @@ func TestDebugf_MultipleMessages(t *testing.T) {
 		t.Fatalf("Expected 'message 3' in third line, got: %q", lines[2])
 	}
 }
+
+func TestSetJSONLogging(t *testing.T) {
+	var buf bytes.Buffer
+	old := Logger
+	Logger = &buf
+	defer func() { Logger = old }()
+	SetJSONLogging(true)
+	defer SetJSONLogging(false)
+
+	logEvent("shutdown_hook_failed", fields{"error": errors.New("boom")}, "hook failed: %v", "boom")
+
+	var ev map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+		t.Fatalf("output %q is not JSON: %v", buf.String(), err)
+	}
+	if ev["event"] != "shutdown_hook_failed" || ev["error"] != "boom" || ev["msg"] != "hook failed: boom" {
+		t.Fatalf("event = %v", ev)
+	}
+	if ev["pid"] != float64(pid) || ev["ts"] == nil {
+		t.Fatalf("event = %v, want pid %d and ts", ev, pid)
+	}
+	if !strings.HasSuffix(buf.String(), "}\n") {
+		t.Fatalf("output %q does not end the object with a newline", buf.String())
+	}
+}
+
+func TestSetJSONLogging_Debugf(t *testing.T) {
+	var buf bytes.Buffer
+	old := Logger
+	Logger = &buf
+	defer func() { Logger = old }()
+	SetJSONLogging(true)
+	defer SetJSONLogging(false)
+
+	debugf("hello %s", "world")
+
+	var ev map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+		t.Fatalf("output %q is not JSON: %v", buf.String(), err)
+	}
+	if ev["event"] != "debug" || ev["msg"] != "hello world" {
+		t.Fatalf("event = %v", ev)
+	}
+}
+
+func TestSetJSONLogging_FieldsAsText(t *testing.T) {
+	var buf bytes.Buffer
+	old := Logger
+	Logger = &buf
+	defer func() { Logger = old }()
+	SetJSONLogging(true)
+	defer SetJSONLogging(false)
+
+	logEvent("shutdown_started", fields{"signal": syscall.SIGTERM, "delay": 1500 * time.Millisecond, "count": 2}, "shutting down")
+
+	var ev map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+		t.Fatalf("output %q is not JSON: %v", buf.String(), err)
+	}
+	if ev["signal"] != syscall.SIGTERM.String() || ev["delay"] != "1.5s" || ev["count"] != float64(2) {
+		t.Fatalf("event = %v, want the signal and delay as text and the count as a number", ev)
+	}
+}
+
+func TestSetJSONLogging_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+	old := Logger
+	Logger = &buf
+	defer func() { Logger = old }()
+	SetJSONLogging(true)
+	SetJSONLogging(false)
+
+	logEvent("shutdown_started", fields{"signal": syscall.SIGTERM}, "Got signal %d, shutting down...", 15)
+	if got := buf.String(); got != "Got signal 15, shutting down...\n" {
+		t.Fatalf("output = %q, want the plain message once JSON logging is disabled", got)
+	}
+}
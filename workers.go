@@ -0,0 +1,101 @@
+package proc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// WorkerGroup runs worker goroutines that are drained when the process
+// shuts down: their context is cancelled as the SIGTERM listeners are
+// notified, and the shutdown waits for them to return, like for any other
+// listener, within the force-quit delay if one is set. It packages the
+// "spin up N workers, drain them on shutdown" pattern:
+//
+//	g := proc.NewWorkerGroup()
+//	for range 4 {
+//		g.Go(func(ctx context.Context) {
+//			for ctx.Err() == nil {
+//				process(ctx, queue.Next(ctx))
+//			}
+//		})
+//	}
+//
+// A WorkerGroup must be created with NewWorkerGroup.
+type WorkerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	active atomic.Int32
+	id     uint32
+
+	// mu orders wg.Add against the wg.Wait of a drain, which must not
+	// overlap while the counter is zero.
+	mu       sync.Mutex
+	draining bool
+}
+
+// NewWorkerGroup returns a WorkerGroup drained by the next shutdown.
+func NewWorkerGroup() *WorkerGroup {
+	g := &WorkerGroup{}
+	g.ctx, g.cancel = context.WithCancel(context.Background())
+	g.id = Once(syscall.SIGTERM, func() {
+		if n := g.Active(); n > 0 {
+			logEvent("workers_draining", fields{"active": n}, "PID %d. Draining %d workers...", pid, n)
+		}
+		g.drain()
+	})
+	return g
+}
+
+// Go runs fn in a new worker goroutine. The context passed to fn is
+// cancelled when the process begins shutting down or Stop is called; fn
+// should then return promptly. A worker started after that gets a
+// cancelled context and is not waited for. Like with the package-level Go,
+// a panic in fn is written to Logger instead of crashing the process.
+func (g *WorkerGroup) Go(fn func(ctx context.Context)) {
+	g.mu.Lock()
+	tracked := !g.draining
+	if tracked {
+		g.wg.Add(1)
+	}
+	g.mu.Unlock()
+	g.active.Add(1)
+	go func() {
+		if tracked {
+			defer g.wg.Done()
+		}
+		defer g.active.Add(-1)
+		defer recovery()
+		fn(g.ctx)
+	}()
+}
+
+// Active returns the number of workers that have not returned yet.
+func (g *WorkerGroup) Active() int {
+	return int(g.active.Load())
+}
+
+// Wait blocks until all workers have returned.
+func (g *WorkerGroup) Wait() {
+	g.wg.Wait()
+}
+
+// Stop cancels the context of the workers, waits for them to return and
+// detaches the group from the shutdown, e.g. when a subsystem is torn
+// down while the process lives on.
+func (g *WorkerGroup) Stop() {
+	Cancel(g.id)
+	g.drain()
+}
+
+// drain stops accepting workers that would be waited for, cancels the
+// context of the running ones and waits for them to return.
+func (g *WorkerGroup) drain() {
+	g.mu.Lock()
+	g.draining = true
+	g.mu.Unlock()
+	g.cancel()
+	g.wg.Wait()
+}
@@ -0,0 +1,83 @@
+package proc
+
+import (
+	"context"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWorkerGroup_DrainedOnShutdown(t *testing.T) {
+	g := NewWorkerGroup()
+	defer g.Stop()
+
+	var drained atomic.Int32
+	started := make(chan struct{}, 3)
+	for range 3 {
+		g.Go(func(ctx context.Context) {
+			started <- struct{}{}
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			drained.Add(1)
+		})
+	}
+	for range 3 {
+		<-started
+	}
+	if n := g.Active(); n != 3 {
+		t.Fatalf("Active() = %d, want 3", n)
+	}
+
+	var atKill int32 = -1
+	err := ShutdownNow(ShutdownOptions{Kill: func(syscall.Signal) error {
+		atKill = drained.Load()
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("ShutdownNow failed: %v", err)
+	}
+	if atKill != 3 || g.Active() != 0 {
+		t.Fatalf("%d workers drained at the kill, %d still active; want all drained", atKill, g.Active())
+	}
+}
+
+func TestWorkerGroup_Stop(t *testing.T) {
+	g := NewWorkerGroup()
+	var stopped atomic.Bool
+	g.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		stopped.Store(true)
+	})
+	g.Go(func(context.Context) { panic("boom") })
+
+	g.Stop()
+	if !stopped.Load() || g.Active() != 0 {
+		t.Fatalf("Stop returned with the worker stopped %v and %d active", stopped.Load(), g.Active())
+	}
+	if CancelN(g.id) != 0 {
+		t.Fatal("Stop should detach the group from the shutdown")
+	}
+
+	// a worker started after Stop sees a cancelled context
+	done := make(chan error, 1)
+	g.Go(func(ctx context.Context) { done <- ctx.Err() })
+	if err := <-done; err == nil {
+		t.Fatal("worker started after Stop got a live context")
+	}
+}
+
+func TestWorkerGroup_GoDuringDrain(t *testing.T) {
+	// Run with -race: starting workers while the group drains must not
+	// race the WaitGroup.
+	g := NewWorkerGroup()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			g.Go(func(context.Context) {})
+		}
+	}()
+	g.Stop()
+	<-done
+}